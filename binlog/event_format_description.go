@@ -40,6 +40,8 @@ type ChecksumAlgorithm byte
 const (
 	// FlavorMySQL is the MySQL db flavor.
 	FlavorMySQL = "MySQL"
+	// FlavorMariaDB is the MariaDB db flavor.
+	FlavorMariaDB = "MariaDB"
 
 	// ChecksumAlgorithmNone means no checksum appened.
 	ChecksumAlgorithmNone ChecksumAlgorithm = 0x00
@@ -59,7 +61,7 @@ func (e *FormatDescriptionEvent) Decode(data []byte) error {
 	e.EventHeaderLength = buf.ReadUint8()
 	e.EventTypeHeaderLengths = buf.ReadStringEOF()
 	e.ServerDetails = ServerDetails{
-		Flavor:            FlavorMySQL,
+		Flavor:            DetectFlavor(e.ServerVersion),
 		Version:           parseVersionNumber(e.ServerVersion),
 		ChecksumAlgorithm: ChecksumAlgorithmUndefined,
 	}
@@ -106,6 +108,15 @@ func (ca ChecksumAlgorithm) String() string {
 	}
 }
 
+// DetectFlavor returns database flavor for a given server version string.
+// Example: 10.5.8-MariaDB-log is a MariaDB server version
+func DetectFlavor(version string) Flavor {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return FlavorMariaDB
+	}
+	return FlavorMySQL
+}
+
 // parseVersionNumber turns string version into a number just like the library
 // mysql_get_server_version function does.
 // Example: 5.7.19-log gets represented as 50719
@@ -0,0 +1,133 @@
+package binlog
+
+import (
+	"errors"
+	"time"
+
+	"github.com/localhots/bocadillo/buffer"
+)
+
+// GTIDEvent is written before every transaction and carries its global
+// transaction identifier. Anonymous GTID events share the same layout but have
+// empty SID and GNO.
+type GTIDEvent struct {
+	// CommitFlag is set when the transaction is committed with a single
+	// statement, e.g. a DDL query.
+	CommitFlag bool
+	SID        SID
+	GNO        uint64
+
+	// Logical timestamps are used by multi-threaded replicas to determine
+	// which transactions could be applied in parallel.
+	LastCommitted  int64
+	SequenceNumber int64
+
+	// Commit timestamps are in microseconds since Unix epoch. Immediate
+	// timestamp is the time of commit on the server that wrote the binary log,
+	// original timestamp is the time of commit on the originating server.
+	// Available starting from MySQL 8.0.1.
+	ImmediateCommitTimestamp uint64
+	OriginalCommitTimestamp  uint64
+
+	// TransactionLength is the size of the transaction in bytes, including
+	// this event. Available starting from MySQL 8.0.2.
+	TransactionLength uint64
+
+	// Server versions are encoded the same way as ServerDetails.Version.
+	// Available starting from MySQL 8.0.14.
+	ImmediateServerVersion uint32
+	OriginalServerVersion  uint32
+}
+
+// PreviousGTIDsEvent is written at the beginning of every binary log file and
+// contains a set of all transactions executed in previous log files.
+type PreviousGTIDsEvent struct {
+	Set GTIDSet
+}
+
+var (
+	// ErrInvalidGTIDEvent is returned when GTID event cannot be parsed.
+	ErrInvalidGTIDEvent = errors.New("GTID event is invalid")
+)
+
+const (
+	logicalTimestampTypeCode = 2
+
+	// Most significant bit of a commit timestamp and a server version signals
+	// that the original value follows the immediate one.
+	commitTimestampOriginalFlag = uint64(1) << 55
+	serverVersionOriginalFlag   = uint32(1) << 31
+)
+
+// Decode decodes given buffer into a GTID event.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Gtid__event.html
+func (e *GTIDEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 1+16+8 {
+		return ErrInvalidGTIDEvent
+	}
+
+	buf := buffer.New(connBuff)
+	e.CommitFlag = buf.ReadUint8() != 0
+	copy(e.SID[:], buf.Read(16))
+	e.GNO = buf.ReadUint64()
+
+	if len(buf.Cur()) < 1+8+8 {
+		return nil
+	}
+	if buf.ReadUint8() == logicalTimestampTypeCode {
+		e.LastCommitted = int64(buf.ReadUint64())
+		e.SequenceNumber = int64(buf.ReadUint64())
+	}
+
+	if len(buf.Cur()) < 7 {
+		return nil
+	}
+	e.ImmediateCommitTimestamp = buf.ReadVarLen64(7)
+	e.OriginalCommitTimestamp = e.ImmediateCommitTimestamp
+	if e.ImmediateCommitTimestamp&commitTimestampOriginalFlag > 0 {
+		e.ImmediateCommitTimestamp &^= commitTimestampOriginalFlag
+		e.OriginalCommitTimestamp = buf.ReadVarLen64(7)
+	}
+
+	if len(buf.Cur()) == 0 {
+		return nil
+	}
+	e.TransactionLength, _, _ = buf.ReadUintLenEnc()
+
+	if len(buf.Cur()) < 4 {
+		return nil
+	}
+	e.ImmediateServerVersion = buf.ReadUint32()
+	e.OriginalServerVersion = e.ImmediateServerVersion
+	if e.ImmediateServerVersion&serverVersionOriginalFlag > 0 {
+		e.ImmediateServerVersion &^= serverVersionOriginalFlag
+		e.OriginalServerVersion = buf.ReadUint32()
+	}
+
+	return nil
+}
+
+// ImmediateCommitTime returns immediate commit timestamp as time. Zero time is
+// returned if the timestamp is not available.
+func (e GTIDEvent) ImmediateCommitTime() time.Time {
+	return microsecondsToTime(e.ImmediateCommitTimestamp)
+}
+
+// OriginalCommitTime returns original commit timestamp as time. Zero time is
+// returned if the timestamp is not available.
+func (e GTIDEvent) OriginalCommitTime() time.Time {
+	return microsecondsToTime(e.OriginalCommitTimestamp)
+}
+
+// Decode decodes given buffer into a previous GTIDs event.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Previous__gtids__event.html
+func (e *PreviousGTIDsEvent) Decode(connBuff []byte) error {
+	return e.Set.Decode(connBuff)
+}
+
+func microsecondsToTime(us uint64) time.Time {
+	if us == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(us/1e6), int64(us%1e6)*1e3)
+}
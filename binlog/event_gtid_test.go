@@ -0,0 +1,86 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGTIDEventDecode(t *testing.T) {
+	sid, _ := ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	header := []byte{
+		0x01, // Commit flag
+		0x3e, 0x11, 0xfa, 0x47, 0x71, 0xca, 0x11, 0xe1, 0x9e, 0x33, 0xc8, 0x0a, 0xa9, 0x42, 0x95, 0x62,
+		0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // GNO
+		0x02,                                           // Logical timestamp type code
+		0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Last committed
+		0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Sequence number
+	}
+	join := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, p := range parts {
+			data = append(data, p...)
+		}
+		return data
+	}
+	base := GTIDEvent{CommitFlag: true, SID: sid, GNO: 42, LastCommitted: 5, SequenceNumber: 6}
+
+	tests := []struct {
+		name string
+		data []byte
+		exp  func(e *GTIDEvent)
+	}{
+		{"5.6", header[:25], func(e *GTIDEvent) {
+			e.LastCommitted, e.SequenceNumber = 0, 0
+		}},
+		{"5.7", header, func(e *GTIDEvent) {}},
+		{"8.0 same server", join(header,
+			[]byte{0x40, 0xe2, 0xa5, 0x07, 0x31, 0xaf, 0x05}, // Commit timestamp
+			[]byte{0xd2},                   // Transaction length
+			[]byte{0xa0, 0x38, 0x01, 0x00}, // Server version
+		), func(e *GTIDEvent) {
+			e.ImmediateCommitTimestamp, e.OriginalCommitTimestamp = 1600000000123456, 1600000000123456
+			e.TransactionLength = 210
+			e.ImmediateServerVersion, e.OriginalServerVersion = 80032, 80032
+		}},
+		{"8.0 replicated", join(header,
+			[]byte{0x40, 0xe2, 0xa5, 0x07, 0x31, 0xaf, 0x85}, // Immediate commit timestamp with a flag
+			[]byte{0x01, 0x00, 0xa4, 0x07, 0x31, 0xaf, 0x05}, // Original commit timestamp
+			[]byte{0xfc, 0xd2, 0x04},                         // Transaction length
+			[]byte{0xa0, 0x38, 0x01, 0x80},                   // Immediate server version with a flag
+			[]byte{0x93, 0x38, 0x01, 0x00},                   // Original server version
+		), func(e *GTIDEvent) {
+			e.ImmediateCommitTimestamp, e.OriginalCommitTimestamp = 1600000000123456, 1600000000000001
+			e.TransactionLength = 1234
+			e.ImmediateServerVersion, e.OriginalServerVersion = 80032, 80019
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exp := base
+			test.exp(&exp)
+			var e GTIDEvent
+			if err := e.Decode(test.data); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(e, exp) {
+				t.Errorf("Expected %+v, got %+v", exp, e)
+			}
+		})
+	}
+
+	var e GTIDEvent
+	if err := e.Decode(header[:20]); err != ErrInvalidGTIDEvent {
+		t.Errorf("Expected truncated event to be invalid, got %v", err)
+	}
+}
+
+func TestGTIDEventCommitTime(t *testing.T) {
+	e := GTIDEvent{ImmediateCommitTimestamp: 1600000000123456}
+	if exp := time.Unix(1600000000, 123456000); !e.ImmediateCommitTime().Equal(exp) {
+		t.Errorf("Expected commit time %v, got %v", exp, e.ImmediateCommitTime())
+	}
+	if !e.OriginalCommitTime().IsZero() {
+		t.Errorf("Expected zero original commit time, got %v", e.OriginalCommitTime())
+	}
+}
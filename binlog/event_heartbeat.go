@@ -0,0 +1,69 @@
+package binlog
+
+import (
+	"errors"
+
+	"github.com/localhots/bocadillo/buffer"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// HeartbeatEvent is sent by master when there are no new events for the
+// duration of the heartbeat period. It is not written to the binary log and
+// only contains current position of the dump thread.
+type HeartbeatEvent struct {
+	Position Position
+}
+
+// Heartbeat event v2 field types.
+const (
+	heartbeatFieldEndMark  = 0
+	heartbeatFieldFile     = 1
+	heartbeatFieldPosition = 2
+)
+
+var (
+	// ErrInvalidHeartbeatEvent is returned when heartbeat event cannot be
+	// parsed.
+	ErrInvalidHeartbeatEvent = errors.New("Heartbeat event is invalid")
+)
+
+// Decode decodes given buffer into a heartbeat event. Event body contains the
+// name of the current binary log file, position is taken from the event
+// header.
+// Spec: https://dev.mysql.com/doc/internals/en/heartbeat-event.html
+func (e *HeartbeatEvent) Decode(connBuff []byte, h EventHeader) error {
+	e.Position = Position{
+		File:   string(buffer.New(connBuff).ReadStringEOF()),
+		Offset: uint64(h.NextOffset),
+	}
+	return nil
+}
+
+// DecodeV2 decodes given buffer into a heartbeat event. Fields are encoded as
+// a type, length and value triplets of length-encoded integers.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classmysql_1_1binlog_1_1event_1_1Heartbeat__event__v2.html
+func (e *HeartbeatEvent) DecodeV2(connBuff []byte) (err error) {
+	defer func() {
+		// Buffer panics when reading past the end of data
+		if recover() != nil {
+			err = ErrInvalidHeartbeatEvent
+		}
+	}()
+
+	buf := buffer.New(connBuff)
+	for len(buf.Cur()) > 0 {
+		typ, _, _ := buf.ReadUintLenEnc()
+		if typ == heartbeatFieldEndMark {
+			break
+		}
+		n, _, _ := buf.ReadUintLenEnc()
+		val := buf.Read(int(n))
+		switch typ {
+		case heartbeatFieldFile:
+			e.Position.File = string(val)
+		case heartbeatFieldPosition:
+			e.Position.Offset, _, _ = mysql.DecodeUintLenEnc(val)
+		}
+	}
+	return nil
+}
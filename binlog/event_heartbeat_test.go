@@ -0,0 +1,30 @@
+package binlog
+
+import "testing"
+
+func TestHeartbeatEventDecode(t *testing.T) {
+	var e HeartbeatEvent
+	if err := e.Decode([]byte("mysql-bin.000002"), EventHeader{NextOffset: 1234}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := (Position{File: "mysql-bin.000002", Offset: 1234}); e.Position != exp {
+		t.Errorf("Expected %v, got %v", exp, e.Position)
+	}
+
+	data := []byte{heartbeatFieldFile, 16}
+	data = append(data, "mysql-bin.000003"...)
+	// Position 5000000000 as a length-encoded integer
+	data = append(data, heartbeatFieldPosition, 9, 0xFE, 0x00, 0xF2, 0x05, 0x2A, 0x01, 0, 0, 0)
+	data = append(data, heartbeatFieldEndMark)
+	e = HeartbeatEvent{}
+	if err := e.DecodeV2(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := (Position{File: "mysql-bin.000003", Offset: 5000000000}); e.Position != exp {
+		t.Errorf("Expected %v, got %v", exp, e.Position)
+	}
+
+	if err := e.DecodeV2(data[:10]); err != ErrInvalidHeartbeatEvent {
+		t.Errorf("Expected invalid event error, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/localhots/bocadillo/buffer"
+)
+
+// IntvarEvent contains the value of an integer session variable that is used
+// by the following query event.
+type IntvarEvent struct {
+	Type  IntvarType
+	Value uint64
+}
+
+// IntvarType defines the variable an intvar event is setting.
+type IntvarType byte
+
+const (
+	// IntvarTypeInvalid is an invalid variable type.
+	IntvarTypeInvalid IntvarType = 0
+	// IntvarTypeLastInsertID is used when the query calls LAST_INSERT_ID().
+	IntvarTypeLastInsertID IntvarType = 1
+	// IntvarTypeInsertID is used when the query inserts into a table with an
+	// AUTO_INCREMENT column, it is the value of the first generated ID.
+	IntvarTypeInsertID IntvarType = 2
+)
+
+var (
+	// ErrInvalidIntvarEvent is returned when intvar event cannot be parsed.
+	ErrInvalidIntvarEvent = errors.New("Intvar event is invalid")
+)
+
+// Decode decodes given buffer into an intvar event.
+// Spec: https://dev.mysql.com/doc/internals/en/intvar-event.html
+func (e *IntvarEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 1+8 {
+		return ErrInvalidIntvarEvent
+	}
+	buf := buffer.New(connBuff)
+	e.Type = IntvarType(buf.ReadUint8())
+	e.Value = buf.ReadUint64()
+	return nil
+}
+
+func (t IntvarType) String() string {
+	switch t {
+	case IntvarTypeInvalid:
+		return "INVALID_INT"
+	case IntvarTypeLastInsertID:
+		return "LAST_INSERT_ID"
+	case IntvarTypeInsertID:
+		return "INSERT_ID"
+	default:
+		return fmt.Sprintf("Unknown(%d)", t)
+	}
+}
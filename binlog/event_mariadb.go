@@ -0,0 +1,197 @@
+package binlog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+
+	"github.com/localhots/bocadillo/buffer"
+)
+
+// MariaDBGTIDEvent marks the beginning of a transaction in MariaDB binary log.
+// It replaces the BEGIN query event, standalone transactions (e.g. DDL) are
+// not followed by a COMMIT query or an XID event.
+type MariaDBGTIDEvent struct {
+	GTID  MariaDBGTID
+	Flags byte
+	// CommitID is set for transactions that were group committed.
+	CommitID uint64
+}
+
+// MariaDBGTIDListEvent is logged at the beginning of each binary log file and
+// contains the replication position at that point.
+type MariaDBGTIDListEvent struct {
+	GTIDs []MariaDBGTID
+}
+
+// BinlogCheckpointEvent contains the name of the oldest binary log file that
+// is still needed for crash recovery.
+type BinlogCheckpointEvent struct {
+	File string
+}
+
+// AnnotateRowsEvent contains the original query of the following rows events.
+type AnnotateRowsEvent struct {
+	Query []byte
+}
+
+// MariaDB GTID event flags.
+const (
+	// MariaDBGTIDFlagStandalone is set for transactions that consist of a
+	// single statement that is not wrapped in BEGIN/COMMIT.
+	MariaDBGTIDFlagStandalone byte = 0x01
+	// MariaDBGTIDFlagGroupCommitID is set if commit ID is present.
+	MariaDBGTIDFlagGroupCommitID byte = 0x02
+	// MariaDBGTIDFlagTransactional is set for transactions that only modify
+	// tables of transactional storage engines.
+	MariaDBGTIDFlagTransactional byte = 0x04
+	// MariaDBGTIDFlagDDL is set for DDL transactions.
+	MariaDBGTIDFlagDDL byte = 0x20
+)
+
+var (
+	// ErrInvalidMariaDBEvent is returned when one of MariaDB specific events
+	// cannot be parsed.
+	ErrInvalidMariaDBEvent = errors.New("MariaDB event is invalid")
+	// ErrInvalidCompressedData is returned when compressed query or rows data
+	// cannot be decompressed.
+	ErrInvalidCompressedData = errors.New("Compressed data is invalid")
+)
+
+// Decode decodes given buffer into a MariaDB GTID event. Server ID part of the
+// GTID is taken from the event header.
+// Spec: https://mariadb.com/kb/en/gtid_event/
+func (e *MariaDBGTIDEvent) Decode(connBuff []byte, serverID uint32) error {
+	if len(connBuff) < 8+4+1 {
+		return ErrInvalidMariaDBEvent
+	}
+	buf := buffer.New(connBuff)
+	e.GTID.SequenceNumber = buf.ReadUint64()
+	e.GTID.DomainID = buf.ReadUint32()
+	e.GTID.ServerID = serverID
+	e.Flags = buf.ReadUint8()
+	if e.Flags&MariaDBGTIDFlagGroupCommitID > 0 {
+		if len(buf.Cur()) < 8 {
+			return ErrInvalidMariaDBEvent
+		}
+		e.CommitID = buf.ReadUint64()
+	}
+	return nil
+}
+
+// IsStandalone returns true if the transaction is not terminated with a
+// COMMIT query or an XID event.
+func (e MariaDBGTIDEvent) IsStandalone() bool {
+	return e.Flags&MariaDBGTIDFlagStandalone > 0
+}
+
+// Decode decodes given buffer into a MariaDB GTID list event.
+// Spec: https://mariadb.com/kb/en/gtid_list_event/
+func (e *MariaDBGTIDListEvent) Decode(connBuff []byte) error {
+	const entrySize = 4 + 4 + 8
+	if len(connBuff) < 4 {
+		return ErrInvalidMariaDBEvent
+	}
+	buf := buffer.New(connBuff)
+	// Higher 4 bits are reserved for flags
+	n := int(buf.ReadUint32() & 0x0FFFFFFF)
+	if len(buf.Cur()) < n*entrySize {
+		return ErrInvalidMariaDBEvent
+	}
+	e.GTIDs = make([]MariaDBGTID, n)
+	for i := range e.GTIDs {
+		e.GTIDs[i].DomainID = buf.ReadUint32()
+		e.GTIDs[i].ServerID = buf.ReadUint32()
+		e.GTIDs[i].SequenceNumber = buf.ReadUint64()
+	}
+	return nil
+}
+
+// Decode decodes given buffer into a binlog checkpoint event.
+// Spec: https://mariadb.com/kb/en/binlog_checkpoint_event/
+func (e *BinlogCheckpointEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 4 {
+		return ErrInvalidMariaDBEvent
+	}
+	buf := buffer.New(connBuff)
+	n := int(buf.ReadUint32())
+	if len(buf.Cur()) < n {
+		return ErrInvalidMariaDBEvent
+	}
+	e.File = string(buf.ReadStringVarLen(n))
+	return nil
+}
+
+// Decode decodes given buffer into an annotate rows event.
+// Spec: https://mariadb.com/kb/en/annotate_rows_event/
+func (e *AnnotateRowsEvent) Decode(connBuff []byte) error {
+	e.Query = buffer.New(connBuff).ReadStringEOF()
+	return nil
+}
+
+// DecodeCompressed decodes given buffer into a query event, query text is
+// decompressed.
+// Spec: https://mariadb.com/kb/en/query_compressed_event/
+func (e *QueryEvent) DecodeCompressed(connBuff []byte) error {
+	if err := e.Decode(connBuff); err != nil {
+		return err
+	}
+	query, err := decompressMariaDB(e.Query)
+	if err != nil {
+		return err
+	}
+	e.Query = query
+	return nil
+}
+
+// decompressMariaDB decompresses data of MariaDB compressed events. First byte
+// of the data contains the compression algorithm in bits 4-6 and the length of
+// the uncompressed data size in bits 0-2. The size follows in big-endian byte
+// order.
+func decompressMariaDB(data []byte) ([]byte, error) {
+	const algZlib = 0
+
+	if len(data) < 1 || data[0]&0x80 == 0 {
+		return nil, ErrInvalidCompressedData
+	}
+	alg := (data[0] & 0x70) >> 4
+	lenlen := int(data[0] & 0x07)
+	if alg != algZlib || lenlen > 4 || len(data) < 1+lenlen {
+		return nil, ErrInvalidCompressedData
+	}
+	var size int
+	for _, b := range data[1 : 1+lenlen] {
+		size = size<<8 | int(b)
+	}
+	if size == 0 {
+		return []byte{}, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data[1+lenlen:]))
+	if err != nil {
+		return nil, ErrInvalidCompressedData
+	}
+	defer zr.Close()
+	out, err := ioutil.ReadAll(zr)
+	if err != nil || len(out) != size {
+		return nil, ErrInvalidCompressedData
+	}
+	return out, nil
+}
+
+// RowsEventIsCompressed returns true if given event is a MariaDB compressed
+// rows event.
+func RowsEventIsCompressed(et EventType) bool {
+	switch et {
+	case EventTypeMariaDBWriteRowsCompressedV1,
+		EventTypeMariaDBUpdateRowsCompressedV1,
+		EventTypeMariaDBDeleteRowsCompressedV1,
+		EventTypeMariaDBWriteRowsCompressed,
+		EventTypeMariaDBUpdateRowsCompressed,
+		EventTypeMariaDBDeleteRowsCompressed:
+		return true
+	default:
+		return false
+	}
+}
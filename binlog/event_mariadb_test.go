@@ -0,0 +1,102 @@
+package binlog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestParseMariaDBGTIDSet(t *testing.T) {
+	s, err := ParseMariaDBGTIDSet("1-2-5, 0-1-100")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := "0-1-100,1-2-5"; s.String() != exp {
+		t.Errorf("Expected %q, got %q", exp, s.String())
+	}
+	s.Update(MariaDBGTID{DomainID: 1, ServerID: 3, SequenceNumber: 6})
+	if g, ok := s.GTID(1); !ok || g.String() != "1-3-6" {
+		t.Errorf("Unexpected GTID for domain 1: %v", g)
+	}
+
+	for _, str := range []string{"0-1", "a-1-2", "0-1-2-3", "0-1-100,"} {
+		if _, err := ParseMariaDBGTIDSet(str); err != ErrInvalidMariaDBGTID {
+			t.Errorf("Expected %q to be invalid, got %v", str, err)
+		}
+	}
+}
+
+func TestMariaDBGTIDEventDecode(t *testing.T) {
+	data := make([]byte, 8+4+1+8)
+	mysql.EncodeUint64(data, 100)
+	mysql.EncodeUint32(data[8:], 1)
+	data[12] = MariaDBGTIDFlagStandalone | MariaDBGTIDFlagGroupCommitID
+	mysql.EncodeUint64(data[13:], 55)
+
+	var e MariaDBGTIDEvent
+	if err := e.Decode(data, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.GTID.String() != "1-2-100" || e.CommitID != 55 || !e.IsStandalone() {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if err := e.Decode(data[:15], 2); err != ErrInvalidMariaDBEvent {
+		t.Errorf("Expected invalid event error, got %v", err)
+	}
+}
+
+func TestMariaDBGTIDListEventDecode(t *testing.T) {
+	data := make([]byte, 4+2*16)
+	mysql.EncodeUint32(data, 2)
+	mysql.EncodeUint32(data[4:], 0)
+	mysql.EncodeUint32(data[8:], 1)
+	mysql.EncodeUint64(data[12:], 100)
+	mysql.EncodeUint32(data[20:], 1)
+	mysql.EncodeUint32(data[24:], 2)
+	mysql.EncodeUint64(data[28:], 5)
+
+	var e MariaDBGTIDListEvent
+	if err := e.Decode(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(e.GTIDs) != 2 || e.GTIDs[0].String() != "0-1-100" || e.GTIDs[1].String() != "1-2-5" {
+		t.Errorf("Unexpected GTIDs: %v", e.GTIDs)
+	}
+}
+
+func TestQueryEventDecodeCompressed(t *testing.T) {
+	query := "INSERT INTO foo VALUES (1)"
+	var zbuf bytes.Buffer
+	zw := zlib.NewWriter(&zbuf)
+	zw.Write([]byte(query))
+	zw.Close()
+
+	compressed := append([]byte{0x80 | 1, byte(len(query))}, zbuf.Bytes()...)
+	data := make([]byte, 13+len("testdb")+1)
+	data[8] = byte(len("testdb"))
+	copy(data[13:], "testdb")
+	data = append(data, compressed...)
+
+	var e QueryEvent
+	if err := e.DecodeCompressed(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(e.Schema) != "testdb" || string(e.Query) != query {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+
+	if err := e.DecodeCompressed(data[:len(data)-1]); err != ErrInvalidCompressedData {
+		t.Errorf("Expected invalid compressed data error, got %v", err)
+	}
+}
+
+func TestDetectFlavor(t *testing.T) {
+	if f := DetectFlavor("10.5.8-MariaDB-log"); f != FlavorMariaDB {
+		t.Errorf("Expected MariaDB flavor, got %s", f)
+	}
+	if f := DetectFlavor("8.0.21"); f != FlavorMySQL {
+		t.Errorf("Expected MySQL flavor, got %s", f)
+	}
+}
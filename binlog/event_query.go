@@ -1,6 +1,8 @@
 package binlog
 
 import (
+	"errors"
+
 	"github.com/localhots/bocadillo/buffer"
 )
 
@@ -9,31 +11,176 @@ type QueryEvent struct {
 	SlaveProxyID  uint32
 	ExecutionTime uint32
 	ErrorCode     uint16
-	StatusVars    []byte
+	StatusVars    QueryStatusVars
 	Schema        []byte
 	Query         []byte
 }
 
+// QueryStatusVars contains session variables that affect query execution.
+// Only variables that differ from defaults are written by the server.
+type QueryStatusVars struct {
+	Flags2  uint32
+	SQLMode uint64
+	Catalog string
+
+	AutoIncrementIncrement uint16
+	AutoIncrementOffset    uint16
+
+	// Collation IDs of character_set_client, collation_connection and
+	// collation_server session variables.
+	CharsetClient       uint16
+	CollationConnection uint16
+	CollationServer     uint16
+
+	TimeZone                   string
+	LCTimeNames                uint16
+	CharsetDatabase            uint16
+	TableMapForUpdate          uint64
+	MasterDataWritten          uint32
+	InvokerUser                string
+	InvokerHost                string
+	UpdatedDBNames             []string
+	Microseconds               uint32
+	ExplicitDefaultsTimestamp  bool
+	DDLXID                     uint64
+	DefaultCollationForUTF8MB4 uint16
+	SQLRequirePrimaryKey       bool
+	DefaultTableEncryption     bool
+}
+
+// Query event status variable codes.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Query__event.html
+const (
+	statusVarFlags2                     = 0
+	statusVarSQLMode                    = 1
+	statusVarCatalog                    = 2
+	statusVarAutoIncrement              = 3
+	statusVarCharset                    = 4
+	statusVarTimeZone                   = 5
+	statusVarCatalogNZ                  = 6
+	statusVarLCTimeNames                = 7
+	statusVarCharsetDatabase            = 8
+	statusVarTableMapForUpdate          = 9
+	statusVarMasterDataWritten          = 10
+	statusVarInvoker                    = 11
+	statusVarUpdatedDBNames             = 12
+	statusVarMicroseconds               = 13
+	statusVarCommitTS                   = 14
+	statusVarCommitTS2                  = 15
+	statusVarExplicitDefaultsTimestamp  = 16
+	statusVarDDLLoggedWithXID           = 17
+	statusVarDefaultCollationForUTF8MB4 = 18
+	statusVarSQLRequirePrimaryKey       = 19
+	statusVarDefaultTableEncryption     = 20
+
+	// overMaxDBsInEventMTS is used instead of updated databases count when
+	// there are too many of them.
+	overMaxDBsInEventMTS = 254
+)
+
+var (
+	// ErrInvalidQueryEvent is returned when query event cannot be parsed.
+	ErrInvalidQueryEvent = errors.New("Query event is invalid")
+)
+
 // Decode given buffer into a qeury event.
 // Spec: https://dev.mysql.com/doc/internals/en/query-event.html
-func (e *QueryEvent) Decode(connBuff []byte) {
+func (e *QueryEvent) Decode(connBuff []byte) error {
+	const postHeaderLen = 4 + 4 + 1 + 2 + 2
+	if len(connBuff) < postHeaderLen {
+		return ErrInvalidQueryEvent
+	}
 	buf := buffer.New(connBuff)
 
 	e.SlaveProxyID = buf.ReadUint32()
 	e.ExecutionTime = buf.ReadUint32()
 	schemaLen := int(buf.ReadUint8())
 	e.ErrorCode = buf.ReadUint16()
-	statusVarLen := int(buf.ReadUint8())
+	statusVarLen := int(buf.ReadUint16())
+	if len(buf.Cur()) < statusVarLen+schemaLen+1 {
+		return ErrInvalidQueryEvent
+	}
 
-	e.StatusVars = make([]byte, statusVarLen)
-	copy(e.StatusVars, buf.Read(statusVarLen))
+	if err := e.StatusVars.Decode(buf.Read(statusVarLen)); err != nil {
+		return err
+	}
 
-	// FIXME: This is not by the spec but seem to work
-	// It could be there's an error somewhere and this byte skipping corrects it
-	buf.Skip(1) // Always 0x00
 	e.Schema = make([]byte, schemaLen)
 	copy(e.Schema, buf.Read(schemaLen))
 
 	buf.Skip(1) // Always 0x00
 	e.Query = buf.Cur()
+	return nil
+}
+
+// Decode decodes given buffer into a set of status variables. Decoding stops at
+// the first unknown variable because its length can't be determined.
+func (v *QueryStatusVars) Decode(data []byte) (err error) {
+	defer func() {
+		// Buffer panics when reading past the end of data
+		if recover() != nil {
+			err = ErrInvalidQueryEvent
+		}
+	}()
+
+	buf := buffer.New(data)
+	for len(buf.Cur()) > 0 {
+		switch buf.ReadUint8() {
+		case statusVarFlags2:
+			v.Flags2 = buf.ReadUint32()
+		case statusVarSQLMode:
+			v.SQLMode = buf.ReadUint64()
+		case statusVarCatalog:
+			v.Catalog = string(buf.ReadStringVarEnc(1))
+			buf.Skip(1) // Always 0x00
+		case statusVarAutoIncrement:
+			v.AutoIncrementIncrement = buf.ReadUint16()
+			v.AutoIncrementOffset = buf.ReadUint16()
+		case statusVarCharset:
+			v.CharsetClient = buf.ReadUint16()
+			v.CollationConnection = buf.ReadUint16()
+			v.CollationServer = buf.ReadUint16()
+		case statusVarTimeZone:
+			v.TimeZone = string(buf.ReadStringVarEnc(1))
+		case statusVarCatalogNZ:
+			v.Catalog = string(buf.ReadStringVarEnc(1))
+		case statusVarLCTimeNames:
+			v.LCTimeNames = buf.ReadUint16()
+		case statusVarCharsetDatabase:
+			v.CharsetDatabase = buf.ReadUint16()
+		case statusVarTableMapForUpdate:
+			v.TableMapForUpdate = buf.ReadUint64()
+		case statusVarMasterDataWritten:
+			v.MasterDataWritten = buf.ReadUint32()
+		case statusVarInvoker:
+			v.InvokerUser = string(buf.ReadStringVarEnc(1))
+			v.InvokerHost = string(buf.ReadStringVarEnc(1))
+		case statusVarUpdatedDBNames:
+			n := int(buf.ReadUint8())
+			if n == overMaxDBsInEventMTS {
+				continue
+			}
+			v.UpdatedDBNames = make([]string, n)
+			for i := range v.UpdatedDBNames {
+				v.UpdatedDBNames[i] = string(buf.ReadStringNullTerm())
+			}
+		case statusVarMicroseconds:
+			v.Microseconds = buf.ReadUint24()
+		case statusVarExplicitDefaultsTimestamp:
+			v.ExplicitDefaultsTimestamp = buf.ReadUint8() != 0
+		case statusVarDDLLoggedWithXID:
+			v.DDLXID = buf.ReadUint64()
+		case statusVarDefaultCollationForUTF8MB4:
+			v.DefaultCollationForUTF8MB4 = buf.ReadUint16()
+		case statusVarSQLRequirePrimaryKey:
+			v.SQLRequirePrimaryKey = buf.ReadUint8() != 0
+		case statusVarDefaultTableEncryption:
+			v.DefaultTableEncryption = buf.ReadUint8() != 0
+		default:
+			// Commit timestamp codes are not used and unknown codes can't be
+			// skipped
+			return nil
+		}
+	}
+	return nil
 }
@@ -0,0 +1,60 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryEventDecode(t *testing.T) {
+	statusVars := []byte{
+		statusVarFlags2, 0x00, 0x00, 0x00, 0x00,
+		statusVarSQLMode, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00,
+		statusVarCatalogNZ, 0x03, 's', 't', 'd',
+		statusVarCharset, 0x21, 0x00, 0x21, 0x00, 0x08, 0x00,
+		statusVarTimeZone, 0x06, 'S', 'Y', 'S', 'T', 'E', 'M',
+		statusVarUpdatedDBNames, 0x01, 't', 'e', 's', 't', 'd', 'b', 0x00,
+		statusVarDDLLoggedWithXID, 0x2A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		statusVarDefaultCollationForUTF8MB4, 0xFF, 0x00,
+	}
+	schema := "testdb"
+	query := "CREATE TABLE foo (id INT)"
+
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00, // Slave proxy ID
+		0x00, 0x00, 0x00, 0x00, // Execution time
+		byte(len(schema)),
+		0x00, 0x00, // Error code
+		byte(len(statusVars)), 0x00,
+	}
+	data = append(data, statusVars...)
+	data = append(data, schema...)
+	data = append(data, 0x00)
+	data = append(data, query...)
+
+	var e QueryEvent
+	if err := e.Decode(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(e.Schema) != schema || string(e.Query) != query {
+		t.Errorf("Expected schema %q and query %q, got %q and %q", schema, query, e.Schema, e.Query)
+	}
+
+	exp := QueryStatusVars{
+		SQLMode:                    0x200000,
+		Catalog:                    "std",
+		CharsetClient:              33,
+		CollationConnection:        33,
+		CollationServer:            8,
+		TimeZone:                   "SYSTEM",
+		UpdatedDBNames:             []string{"testdb"},
+		DDLXID:                     42,
+		DefaultCollationForUTF8MB4: 255,
+	}
+	if !reflect.DeepEqual(e.StatusVars, exp) {
+		t.Errorf("Expected status vars %+v, got %+v", exp, e.StatusVars)
+	}
+
+	if err := e.Decode(data[:len(data)-len(query)-len(schema)-2]); err != ErrInvalidQueryEvent {
+		t.Errorf("Expected truncated event to be invalid, got %v", err)
+	}
+}
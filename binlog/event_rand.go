@@ -0,0 +1,31 @@
+package binlog
+
+import (
+	"errors"
+
+	"github.com/localhots/bocadillo/buffer"
+)
+
+// RandEvent contains the seed values of the random number generator that are
+// used by the following query event that calls RAND().
+type RandEvent struct {
+	Seed1 uint64
+	Seed2 uint64
+}
+
+var (
+	// ErrInvalidRandEvent is returned when rand event cannot be parsed.
+	ErrInvalidRandEvent = errors.New("Rand event is invalid")
+)
+
+// Decode decodes given buffer into a rand event.
+// Spec: https://dev.mysql.com/doc/internals/en/rand-event.html
+func (e *RandEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 8+8 {
+		return ErrInvalidRandEvent
+	}
+	buf := buffer.New(connBuff)
+	e.Seed1 = buf.ReadUint64()
+	e.Seed2 = buf.ReadUint64()
+	return nil
+}
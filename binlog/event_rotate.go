@@ -1,6 +1,11 @@
 package binlog
 
-import "github.com/localhots/bocadillo/buffer"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/localhots/bocadillo/buffer"
+)
 
 // Position is a pair of log file name and a binary offset in it that is used to
 // represent the beginning of the event description.
@@ -9,6 +14,45 @@ type Position struct {
 	Offset uint64
 }
 
+// Compare returns a negative number if the position comes before the given
+// one, a positive number if it comes after it and zero if positions are equal.
+// Binary log file names share the same base name and differ in a sequence
+// number extension that grows past six digits once it exceeds 999999, so
+// extensions are compared as numbers.
+func (p Position) Compare(o Position) int {
+	if c := compareFiles(p.File, o.File); c != 0 {
+		return c
+	}
+	switch {
+	case p.Offset < o.Offset:
+		return -1
+	case p.Offset > o.Offset:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareFiles compares binary log file names by their sequence numbers if
+// both names share the same base name, names are compared as strings
+// otherwise.
+func compareFiles(a, b string) int {
+	ai, bi := strings.LastIndexByte(a, '.'), strings.LastIndexByte(b, '.')
+	if ai >= 0 && bi >= 0 && a[:ai] == b[:bi] {
+		an, aerr := strconv.ParseUint(a[ai+1:], 10, 64)
+		bn, berr := strconv.ParseUint(b[bi+1:], 10, 64)
+		if aerr == nil && berr == nil {
+			switch {
+			case an < bn:
+				return -1
+			case an > bn:
+				return 1
+			}
+		}
+	}
+	return strings.Compare(a, b)
+}
+
 // RotateEvent is written at the end of the file that points to the next file in
 // the squence. It is written when a binary log file exceeds a size limit.
 type RotateEvent struct {
@@ -19,7 +63,9 @@ type RotateEvent struct {
 // Spec: https://dev.mysql.com/doc/internals/en/rotate-event.html
 func (e *RotateEvent) Decode(connBuff []byte, fd FormatDescription) error {
 	buf := buffer.New(connBuff)
-	if fd.Version > 1 {
+	// Format is unknown before the first format description event, fake
+	// rotate events sent ahead of it use the latest version
+	if fd.Version == 0 || fd.Version > 1 {
 		e.NextFile.Offset = buf.ReadUint64()
 	} else {
 		e.NextFile.Offset = 4
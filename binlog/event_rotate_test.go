@@ -0,0 +1,24 @@
+package binlog
+
+import "testing"
+
+func TestPositionCompare(t *testing.T) {
+	tests := []struct {
+		a, b Position
+		exp  int
+	}{
+		{Position{"mysql-bin.000001", 4}, Position{"mysql-bin.000001", 4}, 0},
+		{Position{"mysql-bin.000001", 4}, Position{"mysql-bin.000001", 120}, -1},
+		{Position{"mysql-bin.000002", 4}, Position{"mysql-bin.000001", 120}, 1},
+		// Sequence number grows past six digits
+		{Position{"mysql-bin.999999", 120}, Position{"mysql-bin.1000000", 4}, -1},
+		{Position{"mysql-bin.1000000", 4}, Position{"mysql-bin.999999", 120}, 1},
+		// Names with different base names are compared as strings
+		{Position{"a-bin.000002", 4}, Position{"b-bin.000001", 4}, -1},
+	}
+	for _, test := range tests {
+		if c := test.a.Compare(test.b); c != test.exp {
+			t.Errorf("Expected %v compared to %v to be %d, got %d", test.a, test.b, test.exp, c)
+		}
+	}
+}
@@ -81,20 +81,37 @@ func (e *RowsEvent) Decode(connBuff []byte, fd FormatDescription, td TableDescri
 		e.ColumnBitmap2 = buf.ReadStringVarLen(int(e.ColumnCount+7) / 8)
 	}
 
+	if RowsEventIsCompressed(e.Type) {
+		data, err := decompressMariaDB(buf.Cur())
+		if err != nil {
+			return err
+		}
+		buf = buffer.New(data)
+	}
+
 	e.Rows = make([][]interface{}, 0)
 	for {
-		row, err := e.decodeRows(buf, td, e.ColumnBitmap1)
+		row, err := e.decodeRows(buf, td, e.ColumnBitmap1, nil)
 		if err != nil {
 			return err
 		}
 		e.Rows = append(e.Rows, row)
 
 		if RowsEventHasSecondBitmap(e.Type) {
-			row, err := e.decodeRows(buf, td, e.ColumnBitmap2)
+			var partial []byte
+			if e.Type == EventTypePartialUpdateRows {
+				partial = readPartialBits(buf, td)
+			}
+			after, err := e.decodeRows(buf, td, e.ColumnBitmap2, partial)
 			if err != nil {
 				return err
 			}
-			e.Rows = append(e.Rows, row)
+			if partial != nil {
+				if err := applyJSONDiffs(row, after); err != nil {
+					return err
+				}
+			}
+			e.Rows = append(e.Rows, after)
 		}
 		if !buf.More() {
 			break
@@ -103,7 +120,10 @@ func (e *RowsEvent) Decode(connBuff []byte, fd FormatDescription, td TableDescri
 	return nil
 }
 
-func (e *RowsEvent) decodeRows(buf *buffer.Buffer, td TableDescription, bm []byte) ([]interface{}, error) {
+// decodeRows decodes a single row image. Partial bitmap contains a bit for
+// every JSON column of the table, if the bit is set the value of the column is
+// a set of modifications.
+func (e *RowsEvent) decodeRows(buf *buffer.Buffer, td TableDescription, bm, partial []byte) ([]interface{}, error) {
 	count := 0
 	for i := 0; i < int(e.ColumnCount); i++ {
 		if isBitSet(bm, i) {
@@ -114,8 +134,13 @@ func (e *RowsEvent) decodeRows(buf *buffer.Buffer, td TableDescription, bm []byt
 
 	nullBM := buf.ReadStringVarLen(count)
 	nullIdx := 0
+	jsonIdx := -1
 	row := make([]interface{}, e.ColumnCount)
 	for i := 0; i < int(e.ColumnCount); i++ {
+		ct := mysql.ColumnType(td.ColumnTypes[i])
+		if ct == mysql.ColumnTypeJSON {
+			jsonIdx++
+		}
 		if !isBitSet(bm, i) {
 			continue
 		}
@@ -127,11 +152,62 @@ func (e *RowsEvent) decodeRows(buf *buffer.Buffer, td TableDescription, bm []byt
 			continue
 		}
 
-		row[i] = e.decodeValue(buf, mysql.ColumnType(td.ColumnTypes[i]), td.ColumnMeta[i])
+		if ct == mysql.ColumnTypeJSON && partial != nil && isBitSet(partial, jsonIdx) {
+			diffs, err := mysql.DecodeJSONDiff(buf.ReadStringVarEnc(int(td.ColumnMeta[i])))
+			if err != nil {
+				return nil, err
+			}
+			row[i] = diffs
+			continue
+		}
+
+		row[i] = e.decodeValue(buf, ct, td.ColumnMeta[i])
 	}
 	return row, nil
 }
 
+// readPartialBits reads value options of the after image of a partial update
+// rows event. If partial JSON updates are enabled a bitmap of partially
+// updated JSON columns is returned, otherwise the result is nil.
+func readPartialBits(buf *buffer.Buffer, td TableDescription) []byte {
+	const partialJSONUpdates = 0x01
+
+	opts, _, _ := buf.ReadUintLenEnc()
+	if opts&partialJSONUpdates == 0 {
+		return nil
+	}
+
+	jsonCols := 0
+	for _, ct := range td.ColumnTypes {
+		if mysql.ColumnType(ct) == mysql.ColumnTypeJSON {
+			jsonCols++
+		}
+	}
+	return buf.ReadStringVarLen((jsonCols + 7) / 8)
+}
+
+// applyJSONDiffs replaces JSON modifications in the after image with full
+// documents if the before image contains original values. Modifications are
+// kept as is otherwise.
+func applyJSONDiffs(before, after []interface{}) error {
+	for i, val := range after {
+		diffs, ok := val.([]mysql.JSONDiff)
+		if !ok {
+			continue
+		}
+		doc, ok := before[i].([]byte)
+		if !ok {
+			continue
+		}
+		res, err := mysql.ApplyJSONDiff(doc, diffs)
+		if err != nil {
+			return fmt.Errorf("partial json update of column %d: %w", i, err)
+		}
+		after[i] = res
+	}
+	return nil
+}
+
 func (e *RowsEvent) decodeValue(buf *buffer.Buffer, ct mysql.ColumnType, meta uint16) interface{} {
 	var length int
 	if ct == mysql.ColumnTypeString {
@@ -268,9 +344,16 @@ func RowsEventVersion(et EventType) int {
 	switch et {
 	case EventTypeWriteRowsV0, EventTypeUpdateRowsV0, EventTypeDeleteRowsV0:
 		return 0
-	case EventTypeWriteRowsV1, EventTypeUpdateRowsV1, EventTypeDeleteRowsV1:
+	case EventTypeWriteRowsV1, EventTypeUpdateRowsV1, EventTypeDeleteRowsV1,
+		EventTypeMariaDBWriteRowsCompressedV1,
+		EventTypeMariaDBUpdateRowsCompressedV1,
+		EventTypeMariaDBDeleteRowsCompressedV1:
 		return 1
-	case EventTypeWriteRowsV2, EventTypeUpdateRowsV2, EventTypeDeleteRowsV2:
+	case EventTypeWriteRowsV2, EventTypeUpdateRowsV2, EventTypeDeleteRowsV2,
+		EventTypePartialUpdateRows,
+		EventTypeMariaDBWriteRowsCompressed,
+		EventTypeMariaDBUpdateRowsCompressed,
+		EventTypeMariaDBDeleteRowsCompressed:
 		return 2
 	default:
 		return -1
@@ -287,7 +370,8 @@ func RowsEventHasExtraData(et EventType) bool {
 // contains a second bitmap.
 func RowsEventHasSecondBitmap(et EventType) bool {
 	switch et {
-	case EventTypeUpdateRowsV1, EventTypeUpdateRowsV2:
+	case EventTypeUpdateRowsV1, EventTypeUpdateRowsV2, EventTypePartialUpdateRows,
+		EventTypeMariaDBUpdateRowsCompressedV1, EventTypeMariaDBUpdateRowsCompressed:
 		return true
 	default:
 		return false
@@ -0,0 +1,88 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestPartialUpdateRowsEventDecode(t *testing.T) {
+	td := TableDescription{
+		ColumnCount: 2,
+		ColumnTypes: []byte{byte(mysql.ColumnTypeLong), byte(mysql.ColumnTypeJSON)},
+		ColumnMeta:  []uint16{0, 4},
+	}
+	fd := FormatDescription{EventTypeHeaderLengths: make([]uint8, EventTypePartialUpdateRows)}
+	fd.EventTypeHeaderLengths[EventTypePartialUpdateRows-1] = 10
+
+	// {"a":1} in binary JSON format
+	doc := []byte{0x00, 1, 0, 12, 0, 11, 0, 1, 0, 0x05, 1, 0, 'a'}
+	// Replace $.a with true
+	diff := []byte{byte(mysql.JSONDiffReplace), 3, '$', '.', 'a', 2, 0x04, 0x01}
+
+	data := []byte{
+		1, 0, 0, 0, 0, 0, // Table ID
+		0, 0, // Flags
+		2, 0, // Extra data length
+		2,    // Column count
+		0x03, // Before image columns
+		0x03, // After image columns
+	}
+	// Before image
+	data = append(data, 0x00, 1, 0, 0, 0, byte(len(doc)), 0, 0, 0)
+	data = append(data, doc...)
+	// After image: value options, partial JSON bitmap, NULL bitmap, values
+	data = append(data, 0x01, 0x01, 0x00, 1, 0, 0, 0, byte(len(diff)), 0, 0, 0)
+	data = append(data, diff...)
+
+	e := RowsEvent{Type: EventTypePartialUpdateRows}
+	if err := e.Decode(data, fd, td); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(e.Rows) != 2 {
+		t.Fatalf("Expected 2 row images, got %d", len(e.Rows))
+	}
+	if v, ok := e.Rows[0][1].([]byte); !ok || string(v) != `{"a":1}` {
+		t.Errorf("Unexpected before image value: %v", e.Rows[0][1])
+	}
+	if v, ok := e.Rows[1][1].([]byte); !ok || string(v) != `{"a":true}` {
+		t.Errorf("Unexpected after image value: %v", e.Rows[1][1])
+	}
+
+	// Length of the modifications takes as many bytes as the column
+	// metadata tells, just like the length of a full document
+	td.ColumnMeta[1] = 2
+	short := append([]byte{}, data[:13]...)
+	short = append(short, 0x00, 1, 0, 0, 0, byte(len(doc)), 0)
+	short = append(short, doc...)
+	short = append(short, 0x01, 0x01, 0x00, 1, 0, 0, 0, byte(len(diff)), 0)
+	short = append(short, diff...)
+	if err := e.Decode(short, fd, td); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, ok := e.Rows[1][1].([]byte); !ok || string(v) != `{"a":true}` {
+		t.Errorf("Unexpected after image value: %v", e.Rows[1][1])
+	}
+	td.ColumnMeta[1] = 4
+
+	// Modification that can't be applied to the original document
+	bad := []byte{byte(mysql.JSONDiffReplace), 5, '$', '.', 'a', '.', 'b', 2, 0x04, 0x01}
+	badData := append(append([]byte{}, data[:len(data)-len(diff)-4]...), byte(len(bad)), 0, 0, 0)
+	badData = append(badData, bad...)
+	if err := e.Decode(badData, fd, td); err == nil {
+		t.Errorf("Expected an error applying %v to %s", bad, e.Rows[0][1])
+	}
+
+	// Before image doesn't contain JSON column, modifications are returned
+	// as is
+	data[11] = 0x01
+	after := data[13+9+len(doc):]
+	data = append(append(data[:13:13], 0x00, 1, 0, 0, 0), after...)
+	if err := e.Decode(data, fd, td); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	diffs, ok := e.Rows[1][1].([]mysql.JSONDiff)
+	if !ok || len(diffs) != 1 || diffs[0].Path != "$.a" || string(diffs[0].Value) != "true" {
+		t.Errorf("Unexpected after image value: %v", e.Rows[1][1])
+	}
+}
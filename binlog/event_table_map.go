@@ -1,6 +1,8 @@
 package binlog
 
 import (
+	"errors"
+
 	"github.com/localhots/bocadillo/buffer"
 	"github.com/localhots/bocadillo/mysql"
 )
@@ -14,8 +16,54 @@ type TableDescription struct {
 	ColumnTypes []byte
 	ColumnMeta  []uint16
 	NullBitmask []byte
+
+	// Metadata is nil unless the server logs optional table metadata
+	// (binlog_row_metadata variable of MySQL 8.0).
+	Metadata *TableMetadata
+}
+
+// TableMetadata contains optional table details. Slices are indexed by column
+// position and are nil if the server did not log corresponding details.
+type TableMetadata struct {
+	// Unsigned is true for unsigned numeric columns.
+	Unsigned []bool
+	// Charsets contains collation IDs of character, ENUM and SET columns. It
+	// is zero for other columns.
+	Charsets    []uint64
+	ColumnNames []string
+	SetValues   [][]string
+	EnumValues  [][]string
+	// GeometryTypes contains geometry type codes of spatial columns.
+	GeometryTypes []uint64
+	// PrimaryKey contains positions of primary key columns. PrimaryKeyPrefixes
+	// contains prefix lengths of the same columns, zero means the whole column
+	// value is used.
+	PrimaryKey         []int
+	PrimaryKeyPrefixes []uint64
 }
 
+// Optional metadata field types.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Table__map__event.html
+const (
+	tableMetaSignedness               = 1
+	tableMetaDefaultCharset           = 2
+	tableMetaColumnCharset            = 3
+	tableMetaColumnName               = 4
+	tableMetaSetStrValue              = 5
+	tableMetaEnumStrValue             = 6
+	tableMetaGeometryType             = 7
+	tableMetaSimplePrimaryKey         = 8
+	tableMetaPrimaryKeyWithPrefix     = 9
+	tableMetaEnumAndSetDefaultCharset = 10
+	tableMetaEnumAndSetColumnCharset  = 11
+)
+
+var (
+	// ErrInvalidTableMapEvent is returned when table map event cannot be
+	// parsed.
+	ErrInvalidTableMapEvent = errors.New("Table map event is invalid")
+)
+
 // TableMapEvent contains table description alongside an ID that would be used
 // to reference the table in the following rows events.
 type TableMapEvent struct {
@@ -45,11 +93,208 @@ func (e *TableMapEvent) Decode(connBuff []byte, fd FormatDescription) error {
 	e.ColumnTypes = buf.ReadStringVarLen(int(e.ColumnCount))
 	colMeta, _ := buf.ReadStringLenEnc()
 	e.ColumnMeta = decodeColumnMeta(colMeta, e.ColumnTypes)
-	e.NullBitmask = buf.ReadStringVarLen(int(e.ColumnCount+8) / 7)
+	e.NullBitmask = buf.ReadStringVarLen(int(e.ColumnCount+7) / 8)
+
+	e.Metadata = nil
+	if len(buf.Cur()) > 0 {
+		meta, err := decodeTableMetadata(buf.Cur(), e.TableDescription)
+		if err != nil {
+			return err
+		}
+		e.Metadata = meta
+	}
 
 	return nil
 }
 
+// decodeTableMetadata decodes optional metadata fields. Every field is encoded
+// as a type byte followed by length-encoded value length and the value itself.
+func decodeTableMetadata(data []byte, td TableDescription) (meta *TableMetadata, err error) {
+	defer func() {
+		// Buffer panics when reading past the end of data
+		if recover() != nil {
+			meta, err = nil, ErrInvalidTableMapEvent
+		}
+	}()
+
+	meta = &TableMetadata{}
+	buf := buffer.New(data)
+	for len(buf.Cur()) > 0 {
+		typ := buf.ReadUint8()
+		n, _, _ := buf.ReadUintLenEnc()
+		val := buffer.New(buf.Read(int(n)))
+
+		switch typ {
+		case tableMetaSignedness:
+			meta.Unsigned = make([]bool, td.ColumnCount)
+			bits := val.Cur()
+			j := 0
+			for i := range meta.Unsigned {
+				if !td.isNumeric(i) {
+					continue
+				}
+				meta.Unsigned[i] = bits[j/8]&(0x80>>uint(j%8)) > 0
+				j++
+			}
+		case tableMetaDefaultCharset:
+			meta.decodeDefaultCharset(val, td, td.isCharacter)
+		case tableMetaEnumAndSetDefaultCharset:
+			meta.decodeDefaultCharset(val, td, td.isEnumOrSet)
+		case tableMetaColumnCharset:
+			meta.decodeColumnCharset(val, td, td.isCharacter)
+		case tableMetaEnumAndSetColumnCharset:
+			meta.decodeColumnCharset(val, td, td.isEnumOrSet)
+		case tableMetaColumnName:
+			meta.ColumnNames = make([]string, td.ColumnCount)
+			for i := range meta.ColumnNames {
+				name, _ := val.ReadStringLenEnc()
+				meta.ColumnNames[i] = string(name)
+			}
+		case tableMetaSetStrValue:
+			meta.SetValues = decodeStrValues(val, td, mysql.ColumnTypeSet)
+		case tableMetaEnumStrValue:
+			meta.EnumValues = decodeStrValues(val, td, mysql.ColumnTypeEnum)
+		case tableMetaGeometryType:
+			meta.GeometryTypes = make([]uint64, td.ColumnCount)
+			for i := range meta.GeometryTypes {
+				if td.realType(i) == mysql.ColumnTypeGeometry {
+					meta.GeometryTypes[i], _, _ = val.ReadUintLenEnc()
+				}
+			}
+		case tableMetaSimplePrimaryKey:
+			for len(val.Cur()) > 0 {
+				col, _, _ := val.ReadUintLenEnc()
+				meta.PrimaryKey = append(meta.PrimaryKey, int(col))
+				meta.PrimaryKeyPrefixes = append(meta.PrimaryKeyPrefixes, 0)
+			}
+		case tableMetaPrimaryKeyWithPrefix:
+			for len(val.Cur()) > 0 {
+				col, _, _ := val.ReadUintLenEnc()
+				prefix, _, _ := val.ReadUintLenEnc()
+				meta.PrimaryKey = append(meta.PrimaryKey, int(col))
+				meta.PrimaryKeyPrefixes = append(meta.PrimaryKeyPrefixes, prefix)
+			}
+		default:
+			// Unknown fields are skipped, their length is known
+		}
+	}
+	return meta, nil
+}
+
+// decodeDefaultCharset decodes the default collation followed by pairs of
+// column index and collation for columns that use a different one. Column
+// index only counts columns matched by the filter.
+func (m *TableMetadata) decodeDefaultCharset(buf *buffer.Buffer, td TableDescription, filter func(int) bool) {
+	if m.Charsets == nil {
+		m.Charsets = make([]uint64, td.ColumnCount)
+	}
+	cols := filteredColumns(td, filter)
+	def, _, _ := buf.ReadUintLenEnc()
+	for _, i := range cols {
+		m.Charsets[i] = def
+	}
+	for len(buf.Cur()) > 0 {
+		idx, _, _ := buf.ReadUintLenEnc()
+		cs, _, _ := buf.ReadUintLenEnc()
+		if int(idx) < len(cols) {
+			m.Charsets[cols[idx]] = cs
+		}
+	}
+}
+
+// decodeColumnCharset decodes collations of every column matched by the
+// filter.
+func (m *TableMetadata) decodeColumnCharset(buf *buffer.Buffer, td TableDescription, filter func(int) bool) {
+	if m.Charsets == nil {
+		m.Charsets = make([]uint64, td.ColumnCount)
+	}
+	for _, i := range filteredColumns(td, filter) {
+		m.Charsets[i], _, _ = buf.ReadUintLenEnc()
+	}
+}
+
+func decodeStrValues(buf *buffer.Buffer, td TableDescription, ct mysql.ColumnType) [][]string {
+	vals := make([][]string, td.ColumnCount)
+	for i := range vals {
+		if td.realType(i) != ct {
+			continue
+		}
+		n, _, _ := buf.ReadUintLenEnc()
+		vals[i] = make([]string, n)
+		for j := range vals[i] {
+			str, _ := buf.ReadStringLenEnc()
+			vals[i][j] = string(str)
+		}
+	}
+	return vals
+}
+
+func filteredColumns(td TableDescription, filter func(int) bool) []int {
+	var cols []int
+	for i := 0; i < int(td.ColumnCount); i++ {
+		if filter(i) {
+			cols = append(cols, i)
+		}
+	}
+	return cols
+}
+
+// realType returns the type of the column at given position. ENUM and SET
+// columns are logged as strings with the real type stored in metadata.
+func (td TableDescription) realType(i int) mysql.ColumnType {
+	ct := mysql.ColumnType(td.ColumnTypes[i])
+	if ct == mysql.ColumnTypeString && td.ColumnMeta[i] > 0xFF {
+		typeByte := uint8(td.ColumnMeta[i] >> 8)
+		if typeByte&0x30 != 0x30 {
+			// Long CHAR columns use these bits for length
+			return mysql.ColumnType(typeByte | 0x30)
+		}
+		return mysql.ColumnType(typeByte)
+	}
+	return ct
+}
+
+func (td TableDescription) isNumeric(i int) bool {
+	switch td.realType(i) {
+	case mysql.ColumnTypeTiny,
+		mysql.ColumnTypeShort,
+		mysql.ColumnTypeInt24,
+		mysql.ColumnTypeLong,
+		mysql.ColumnTypeLonglong,
+		mysql.ColumnTypeFloat,
+		mysql.ColumnTypeDouble,
+		mysql.ColumnTypeDecimal,
+		mysql.ColumnTypeNewDecimal:
+		return true
+	default:
+		return false
+	}
+}
+
+func (td TableDescription) isCharacter(i int) bool {
+	switch td.realType(i) {
+	case mysql.ColumnTypeString,
+		mysql.ColumnTypeVarstring,
+		mysql.ColumnTypeVarchar,
+		mysql.ColumnTypeBlob,
+		mysql.ColumnTypeTinyblob,
+		mysql.ColumnTypeMediumblob,
+		mysql.ColumnTypeLongblob:
+		return true
+	default:
+		return false
+	}
+}
+
+func (td TableDescription) isEnumOrSet(i int) bool {
+	switch td.realType(i) {
+	case mysql.ColumnTypeEnum, mysql.ColumnTypeSet:
+		return true
+	default:
+		return false
+	}
+}
+
 func decodeColumnMeta(data []byte, cols []byte) []uint16 {
 	pos := 0
 	meta := make([]uint16, len(cols))
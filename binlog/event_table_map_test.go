@@ -0,0 +1,78 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestTableMapEventDecode(t *testing.T) {
+	data := []byte{
+		1, 0, 0, 0, 0, 0, // Table ID
+		0, 0, // Flags
+		6, 't', 'e', 's', 't', 'd', 'b', 0,
+		5, 'u', 's', 'e', 'r', 's', 0,
+		6, // Column count
+		byte(mysql.ColumnTypeLong),
+		byte(mysql.ColumnTypeVarchar),
+		byte(mysql.ColumnTypeString),
+		byte(mysql.ColumnTypeString),
+		byte(mysql.ColumnTypeGeometry),
+		byte(mysql.ColumnTypeTiny),
+		7, 40, 0, byte(mysql.ColumnTypeEnum), 1, byte(mysql.ColumnTypeSet), 1, 4, // Column meta
+		0x3F, // NULL bitmask
+	}
+	meta := [][]byte{
+		{tableMetaSignedness, 1, 0x80},
+		{tableMetaDefaultCharset, 3, 45, 0, 33},
+		{tableMetaEnumAndSetColumnCharset, 2, 8, 63},
+		{tableMetaColumnName, 29, 2, 'i', 'd', 4, 'n', 'a', 'm', 'e', 6, 's', 't', 'a', 't', 'u', 's',
+			4, 't', 'a', 'g', 's', 1, 'g', 6, 'a', 'm', 'o', 'u', 'n', 't'},
+		{tableMetaSetStrValue, 3, 1, 1, 'x'},
+		{tableMetaEnumStrValue, 5, 2, 1, 'a', 1, 'b'},
+		{tableMetaGeometryType, 1, 3},
+		{tableMetaPrimaryKeyWithPrefix, 4, 0, 0, 1, 4},
+		{12, 1, 0xFF}, // Column visibility is not supported
+	}
+	headerLen := len(data)
+	for _, m := range meta {
+		data = append(data, m...)
+	}
+
+	fd := FormatDescription{EventTypeHeaderLengths: make([]uint8, EventTypeTableMap)}
+	fd.EventTypeHeaderLengths[EventTypeTableMap-1] = 8
+
+	var e TableMapEvent
+	if err := e.Decode(data, fd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.TableID != 1 || e.SchemaName != "testdb" || e.TableName != "users" || e.ColumnCount != 6 {
+		t.Errorf("Unexpected table details: %+v", e.TableDescription)
+	}
+	if len(e.NullBitmask) != 1 || e.NullBitmask[0] != 0x3F {
+		t.Errorf("Unexpected NULL bitmask: %v", e.NullBitmask)
+	}
+
+	exp := &TableMetadata{
+		Unsigned:           []bool{true, false, false, false, false, false},
+		Charsets:           []uint64{0, 33, 8, 63, 0, 0},
+		ColumnNames:        []string{"id", "name", "status", "tags", "g", "amount"},
+		SetValues:          [][]string{nil, nil, nil, {"x"}, nil, nil},
+		EnumValues:         [][]string{nil, nil, {"a", "b"}, nil, nil, nil},
+		GeometryTypes:      []uint64{0, 0, 0, 0, 3, 0},
+		PrimaryKey:         []int{0, 1},
+		PrimaryKeyPrefixes: []uint64{0, 4},
+	}
+	if diff := cmp.Diff(exp, e.Metadata); diff != "" {
+		t.Errorf("Metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	// Metadata is not logged
+	if err := e.Decode(data[:headerLen], fd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.Metadata != nil {
+		t.Errorf("Expected no metadata, got %+v", e.Metadata)
+	}
+}
@@ -0,0 +1,140 @@
+package binlog
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/localhots/bocadillo/buffer"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// TransactionPayloadEvent contains a compressed transaction. Payload is a
+// sequence of events written without checksums.
+type TransactionPayloadEvent struct {
+	CompressionType  CompressionType
+	PayloadSize      uint64
+	UncompressedSize uint64
+	Payload          []byte
+}
+
+// CompressionType is a compression algorithm used for transaction payload.
+type CompressionType uint64
+
+const (
+	// CompressionTypeZSTD means payload is compressed using zstd.
+	CompressionTypeZSTD CompressionType = 0
+	// CompressionTypeNone means payload is not compressed.
+	CompressionTypeNone CompressionType = 255
+)
+
+// Transaction payload event field types.
+const (
+	payloadFieldEndMark          = 0
+	payloadFieldPayloadSize      = 1
+	payloadFieldCompressionType  = 2
+	payloadFieldUncompressedSize = 3
+)
+
+var (
+	// ErrInvalidTransactionPayloadEvent is returned when transaction payload
+	// event cannot be parsed or decompressed.
+	ErrInvalidTransactionPayloadEvent = errors.New("Transaction payload event is invalid")
+	// ErrUnsupportedCompression is returned when transaction payload is
+	// compressed with an unknown algorithm.
+	ErrUnsupportedCompression = errors.New("Unsupported compression type")
+)
+
+// maxPayloadPrealloc limits the buffer allocated upfront for decompressed
+// data, uncompressed size comes from the event and is not trusted.
+const maxPayloadPrealloc = 16 << 20
+
+var (
+	// Decoder is safe for concurrent use
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+// Decode decodes given buffer into a transaction payload event. Header fields
+// are encoded as a type, length and value triplets of length-encoded
+// integers.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Transaction__payload__event.html
+func (e *TransactionPayloadEvent) Decode(connBuff []byte) (err error) {
+	defer func() {
+		// Buffer panics when reading past the end of data
+		if recover() != nil {
+			err = ErrInvalidTransactionPayloadEvent
+		}
+	}()
+
+	buf := buffer.New(connBuff)
+	for {
+		typ, _, _ := buf.ReadUintLenEnc()
+		if typ == payloadFieldEndMark {
+			break
+		}
+		n, _, _ := buf.ReadUintLenEnc()
+		val := buf.Read(int(n))
+		switch typ {
+		case payloadFieldPayloadSize:
+			e.PayloadSize, _, _ = mysql.DecodeUintLenEnc(val)
+		case payloadFieldCompressionType:
+			ct, _, _ := mysql.DecodeUintLenEnc(val)
+			e.CompressionType = CompressionType(ct)
+		case payloadFieldUncompressedSize:
+			e.UncompressedSize, _, _ = mysql.DecodeUintLenEnc(val)
+		}
+	}
+
+	e.Payload = buf.Cur()
+	if e.PayloadSize > 0 && uint64(len(e.Payload)) != e.PayloadSize {
+		return ErrInvalidTransactionPayloadEvent
+	}
+	return nil
+}
+
+// Events decompresses the payload and splits it into separate events.
+func (e *TransactionPayloadEvent) Events() ([][]byte, error) {
+	var data []byte
+	switch e.CompressionType {
+	case CompressionTypeNone:
+		data = e.Payload
+	case CompressionTypeZSTD:
+		zstdDecoderOnce.Do(func() {
+			zstdDecoder, zstdDecoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		})
+		if zstdDecoderErr != nil {
+			return nil, zstdDecoderErr
+		}
+		size := e.UncompressedSize
+		if size > maxPayloadPrealloc {
+			size = maxPayloadPrealloc
+		}
+		var err error
+		data, err = zstdDecoder.DecodeAll(e.Payload, make([]byte, 0, size))
+		if err != nil {
+			return nil, ErrInvalidTransactionPayloadEvent
+		}
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+
+	const (
+		headerLen  = 19
+		sizeOffset = 9
+	)
+	var events [][]byte
+	for len(data) > 0 {
+		if len(data) < headerLen {
+			return nil, ErrInvalidTransactionPayloadEvent
+		}
+		size := int(mysql.DecodeUint32(data[sizeOffset:]))
+		if size < headerLen || size > len(data) {
+			return nil, ErrInvalidTransactionPayloadEvent
+		}
+		events = append(events, data[:size])
+		data = data[size:]
+	}
+	return events, nil
+}
@@ -0,0 +1,40 @@
+package binlog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestTransactionPayloadEventEvents(t *testing.T) {
+	evt := make([]byte, 19+4)
+	mysql.EncodeUint32(evt[9:], uint32(len(evt)))
+	enc, _ := zstd.NewWriter(nil)
+	payload := enc.EncodeAll(append(evt, evt...), nil)
+
+	// Payload size, compression type, uncompressed size (way off), end mark
+	data := []byte{1, 1, byte(len(payload)), 2, 1, 0, 3, 9, 0xfe, 0, 0, 0, 0, 0, 0, 0, 0x40, 0}
+	data = append(data, payload...)
+
+	var e TransactionPayloadEvent
+	if err := e.Decode(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.UncompressedSize != 1<<62 {
+		t.Errorf("Expected uncompressed size %d, got %d", uint64(1<<62), e.UncompressedSize)
+	}
+	events, err := e.Events()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 2 || !bytes.Equal(events[0], evt) || !bytes.Equal(events[1], evt) {
+		t.Errorf("Expected two events, got %v", events)
+	}
+
+	e.Payload = e.Payload[:len(e.Payload)-1]
+	if _, err := e.Events(); err != ErrInvalidTransactionPayloadEvent {
+		t.Errorf("Expected error %v, got %v", ErrInvalidTransactionPayloadEvent, err)
+	}
+}
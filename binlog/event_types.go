@@ -127,6 +127,62 @@ const (
 	EventTypeAnonymousGTID EventType = 34
 	// EventTypePreviousGTIDs is a subclass of GTIDEvent.
 	EventTypePreviousGTIDs EventType = 35
+	// EventTypeTransactionContext is used for certification in group
+	// replication.
+	EventTypeTransactionContext EventType = 36
+	// EventTypeViewChange is used for group membership changes in group
+	// replication.
+	EventTypeViewChange EventType = 37
+	// EventTypeXAPrepare is written when an XA transaction is prepared.
+	EventTypeXAPrepare EventType = 38
+	// EventTypePartialUpdateRows represents updated rows where JSON columns
+	// are logged as a set of modifications instead of full values. Used
+	// starting from MySQL 8.0 when binlog_row_value_options=PARTIAL_JSON.
+	EventTypePartialUpdateRows EventType = 39
+	// EventTypeTransactionPayload contains a whole transaction compressed.
+	// Used starting from MySQL 8.0.20 when binlog_transaction_compression is
+	// enabled.
+	EventTypeTransactionPayload EventType = 40
+	// EventTypeHeartbeatV2 replaces HeartbeatEvent starting from MySQL
+	// 8.0.26, binary log position is no longer limited to 4 bytes.
+	EventTypeHeartbeatV2 EventType = 41
+
+	// EventTypeMariaDBAnnotateRows contains the original query of the
+	// following rows events.
+	EventTypeMariaDBAnnotateRows EventType = 160
+	// EventTypeMariaDBBinlogCheckpoint contains the name of the oldest binary
+	// log file needed for crash recovery.
+	EventTypeMariaDBBinlogCheckpoint EventType = 161
+	// EventTypeMariaDBGTID marks the beginning of a transaction and contains
+	// its MariaDB GTID.
+	EventTypeMariaDBGTID EventType = 162
+	// EventTypeMariaDBGTIDList contains the replication position at the
+	// beginning of a binary log file.
+	EventTypeMariaDBGTIDList EventType = 163
+	// EventTypeMariaDBStartEncryption marks the beginning of encrypted binary
+	// log data.
+	EventTypeMariaDBStartEncryption EventType = 164
+	// EventTypeMariaDBQueryCompressed is a query event with compressed query
+	// text.
+	EventTypeMariaDBQueryCompressed EventType = 165
+	// EventTypeMariaDBWriteRowsCompressedV1 is a compressed version of
+	// WriteRowsV1 event.
+	EventTypeMariaDBWriteRowsCompressedV1 EventType = 166
+	// EventTypeMariaDBUpdateRowsCompressedV1 is a compressed version of
+	// UpdateRowsV1 event.
+	EventTypeMariaDBUpdateRowsCompressedV1 EventType = 167
+	// EventTypeMariaDBDeleteRowsCompressedV1 is a compressed version of
+	// DeleteRowsV1 event.
+	EventTypeMariaDBDeleteRowsCompressedV1 EventType = 168
+	// EventTypeMariaDBWriteRowsCompressed is a compressed version of
+	// WriteRowsV2 event.
+	EventTypeMariaDBWriteRowsCompressed EventType = 169
+	// EventTypeMariaDBUpdateRowsCompressed is a compressed version of
+	// UpdateRowsV2 event.
+	EventTypeMariaDBUpdateRowsCompressed EventType = 170
+	// EventTypeMariaDBDeleteRowsCompressed is a compressed version of
+	// DeleteRowsV2 event.
+	EventTypeMariaDBDeleteRowsCompressed EventType = 171
 )
 
 func (et EventType) String() string {
@@ -203,6 +259,42 @@ func (et EventType) String() string {
 		return "AnonymousGTIDEvent"
 	case EventTypePreviousGTIDs:
 		return "PreviousGTIDsEvent"
+	case EventTypeTransactionContext:
+		return "TransactionContextEvent"
+	case EventTypeViewChange:
+		return "ViewChangeEvent"
+	case EventTypeXAPrepare:
+		return "XAPrepareEvent"
+	case EventTypePartialUpdateRows:
+		return "PartialUpdateRowsEvent"
+	case EventTypeTransactionPayload:
+		return "TransactionPayloadEvent"
+	case EventTypeHeartbeatV2:
+		return "HeartbeatV2Event"
+	case EventTypeMariaDBAnnotateRows:
+		return "MariaDBAnnotateRowsEvent"
+	case EventTypeMariaDBBinlogCheckpoint:
+		return "MariaDBBinlogCheckpointEvent"
+	case EventTypeMariaDBGTID:
+		return "MariaDBGTIDEvent"
+	case EventTypeMariaDBGTIDList:
+		return "MariaDBGTIDListEvent"
+	case EventTypeMariaDBStartEncryption:
+		return "MariaDBStartEncryptionEvent"
+	case EventTypeMariaDBQueryCompressed:
+		return "MariaDBQueryCompressedEvent"
+	case EventTypeMariaDBWriteRowsCompressedV1:
+		return "MariaDBWriteRowsCompressedEventV1"
+	case EventTypeMariaDBUpdateRowsCompressedV1:
+		return "MariaDBUpdateRowsCompressedEventV1"
+	case EventTypeMariaDBDeleteRowsCompressedV1:
+		return "MariaDBDeleteRowsCompressedEventV1"
+	case EventTypeMariaDBWriteRowsCompressed:
+		return "MariaDBWriteRowsCompressedEvent"
+	case EventTypeMariaDBUpdateRowsCompressed:
+		return "MariaDBUpdateRowsCompressedEvent"
+	case EventTypeMariaDBDeleteRowsCompressed:
+		return "MariaDBDeleteRowsCompressedEvent"
 	default:
 		return fmt.Sprintf("Unknown(%d)", et)
 	}
@@ -0,0 +1,128 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/localhots/bocadillo/buffer"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// UserVarEvent contains the value of a user variable that is used by the
+// following query event.
+type UserVarEvent struct {
+	Name     string
+	IsNull   bool
+	Type     UserVarType
+	Charset  uint32
+	Unsigned bool
+	// Value is nil, string, float64, int64, uint64 or mysql.Decimal depending
+	// on the type.
+	Value interface{}
+}
+
+// UserVarType is a type of the user variable value.
+type UserVarType byte
+
+const (
+	// UserVarTypeString is a string value.
+	UserVarTypeString UserVarType = 0
+	// UserVarTypeReal is a floating point value.
+	UserVarTypeReal UserVarType = 1
+	// UserVarTypeInt is an integer value.
+	UserVarTypeInt UserVarType = 2
+	// UserVarTypeRow is a row value, it is never used for user variables.
+	UserVarTypeRow UserVarType = 3
+	// UserVarTypeDecimal is a decimal value.
+	UserVarTypeDecimal UserVarType = 4
+)
+
+const (
+	userVarFlagUnsigned = 0x01
+)
+
+var (
+	// ErrInvalidUserVarEvent is returned when user variable event cannot be
+	// parsed.
+	ErrInvalidUserVarEvent = errors.New("User variable event is invalid")
+)
+
+// Decode decodes given buffer into a user variable event.
+// Spec: https://dev.mysql.com/doc/internals/en/user-var-event.html
+func (e *UserVarEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 4 {
+		return ErrInvalidUserVarEvent
+	}
+	buf := buffer.New(connBuff)
+	nameLen := int(buf.ReadUint32())
+	if len(buf.Cur()) < nameLen+1 {
+		return ErrInvalidUserVarEvent
+	}
+	e.Name = string(buf.Read(nameLen))
+	e.IsNull = buf.ReadUint8() != 0
+	if e.IsNull {
+		return nil
+	}
+
+	if len(buf.Cur()) < 1+4+4 {
+		return ErrInvalidUserVarEvent
+	}
+	e.Type = UserVarType(buf.ReadUint8())
+	e.Charset = buf.ReadUint32()
+	valLen := int(buf.ReadUint32())
+	if len(buf.Cur()) < valLen {
+		return ErrInvalidUserVarEvent
+	}
+	val := buf.Read(valLen)
+	if len(buf.Cur()) > 0 {
+		e.Unsigned = buf.ReadUint8()&userVarFlagUnsigned > 0
+	}
+
+	switch e.Type {
+	case UserVarTypeString:
+		e.Value = string(val)
+	case UserVarTypeReal:
+		if len(val) < 8 {
+			return ErrInvalidUserVarEvent
+		}
+		e.Value = mysql.DecodeFloat64(val)
+	case UserVarTypeInt:
+		if len(val) < 8 {
+			return ErrInvalidUserVarEvent
+		}
+		if e.Unsigned {
+			e.Value = mysql.DecodeUint64(val)
+		} else {
+			e.Value = mysql.SignUint64(mysql.DecodeUint64(val))
+		}
+	case UserVarTypeDecimal:
+		if len(val) < 2 {
+			return ErrInvalidUserVarEvent
+		}
+		precision, decimals := int(val[0]), int(val[1])
+		if precision == 0 || decimals > precision || len(val)-2 < mysql.DecimalSize(precision, decimals) {
+			return ErrInvalidUserVarEvent
+		}
+		e.Value, _ = mysql.DecodeDecimal(val[2:], precision, decimals)
+	default:
+		return fmt.Errorf("unsupported user variable type: %d", e.Type)
+	}
+	return nil
+}
+
+func (t UserVarType) String() string {
+	switch t {
+	case UserVarTypeString:
+		return "STRING"
+	case UserVarTypeReal:
+		return "REAL"
+	case UserVarTypeInt:
+		return "INT"
+	case UserVarTypeRow:
+		return "ROW"
+	case UserVarTypeDecimal:
+		return "DECIMAL"
+	default:
+		return fmt.Sprintf("Unknown(%d)", t)
+	}
+}
@@ -0,0 +1,60 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestUserVarEventDecode(t *testing.T) {
+	encode := func(name string, typ UserVarType, val []byte, flags byte) []byte {
+		data := make([]byte, 4+len(name)+1+1+4+4+len(val)+1)
+		mysql.EncodeUint32(data, uint32(len(name)))
+		pos := 4 + copy(data[4:], name) + 1
+		data[pos] = byte(typ)
+		mysql.EncodeUint32(data[pos+1:], 33)
+		mysql.EncodeUint32(data[pos+5:], uint32(len(val)))
+		copy(data[pos+9:], val)
+		data[len(data)-1] = flags
+		return data
+	}
+	intVal := func(v uint64) []byte {
+		b := make([]byte, 8)
+		mysql.EncodeUint64(b, v)
+		return b
+	}
+
+	inputs := []struct {
+		data []byte
+		exp  interface{}
+	}{
+		{encode("foo", UserVarTypeString, []byte("bar"), 0), "bar"},
+		{encode("foo", UserVarTypeInt, intVal(0xFFFFFFFFFFFFFFFF), 0), int64(-1)},
+		{encode("foo", UserVarTypeInt, intVal(0xFFFFFFFFFFFFFFFF), userVarFlagUnsigned), uint64(0xFFFFFFFFFFFFFFFF)},
+		{encode("foo", UserVarTypeReal, intVal(0x3FF8000000000000), 0), float64(1.5)},
+		{encode("foo", UserVarTypeDecimal, []byte{4, 2, 0x81, 0x32}, 0), mysql.NewDecimal("1.50")},
+	}
+	for _, in := range inputs {
+		var e UserVarEvent
+		if err := e.Decode(in.data); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		if e.Name != "foo" || e.Charset != 33 || e.IsNull {
+			t.Errorf("Unexpected event details: %+v", e)
+		}
+		if e.Value != in.exp {
+			t.Errorf("Expected value %v (%T), got %v (%T)", in.exp, in.exp, e.Value, e.Value)
+		}
+	}
+
+	var e UserVarEvent
+	if err := e.Decode([]byte{3, 0, 0, 0, 'f', 'o', 'o', 1}); err != nil || !e.IsNull || e.Value != nil {
+		t.Errorf("Expected a NULL value, got %+v (%v)", e, err)
+	}
+
+	// Decimal value is shorter than its precision requires
+	if err := e.Decode(encode("foo", UserVarTypeDecimal, []byte{20, 2, 0x81, 0x32}, 0)); err != ErrInvalidUserVarEvent {
+		t.Errorf("Expected error %v, got %v", ErrInvalidUserVarEvent, err)
+	}
+}
@@ -1,6 +1,10 @@
 package binlog
 
-import "github.com/localhots/bocadillo/mysql"
+import (
+	"errors"
+
+	"github.com/localhots/bocadillo/mysql"
+)
 
 // XIDEvent contains an XID (XA transaction identifier)
 // https://dev.mysql.com/doc/refman/5.7/en/xa.html
@@ -8,8 +12,17 @@ type XIDEvent struct {
 	XID uint64
 }
 
+var (
+	// ErrInvalidXIDEvent is returned when XID event cannot be parsed.
+	ErrInvalidXIDEvent = errors.New("XID event is invalid")
+)
+
 // Decode decodes given buffer into an XID event.
 // Spec: https://dev.mysql.com/doc/internals/en/xid-event.html
-func (e *XIDEvent) Decode(connBuff []byte) {
+func (e *XIDEvent) Decode(connBuff []byte) error {
+	if len(connBuff) < 8 {
+		return ErrInvalidXIDEvent
+	}
 	e.XID = mysql.DecodeUint64(connBuff)
+	return nil
 }
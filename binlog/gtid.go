@@ -0,0 +1,320 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/localhots/bocadillo/buffer"
+)
+
+// SID is a source identifier, a UUID of the server that originated the
+// transaction.
+type SID [16]byte
+
+// GTIDInterval is a closed range of transaction numbers (GNO) originated by
+// the same server.
+type GTIDInterval struct {
+	Start uint64
+	End   uint64
+}
+
+// GTIDSet is a set of global transaction identifiers grouped by source.
+type GTIDSet struct {
+	sets map[SID][]GTIDInterval
+}
+
+var (
+	// ErrInvalidGTIDSet is returned when binary representation of a GTID set
+	// cannot be parsed.
+	ErrInvalidGTIDSet = errors.New("GTID set is invalid")
+)
+
+// Decode decodes binary representation of a GTID set.
+// Spec: https://dev.mysql.com/doc/internals/en/com-binlog-dump-gtid.html
+func (s *GTIDSet) Decode(data []byte) error {
+	if len(data) < 8 {
+		return ErrInvalidGTIDSet
+	}
+	buf := buffer.New(data)
+	nsids := buf.ReadUint64()
+	// Counts are checked against the size of the data before anything is
+	// allocated, each source takes at least 24 bytes and each interval 16
+	if nsids > uint64(len(buf.Cur()))/(16+8) {
+		return ErrInvalidGTIDSet
+	}
+	s.sets = make(map[SID][]GTIDInterval, nsids)
+	for i := uint64(0); i < nsids; i++ {
+		if len(buf.Cur()) < 16+8 {
+			return ErrInvalidGTIDSet
+		}
+		var sid SID
+		copy(sid[:], buf.Read(16))
+		nivals := buf.ReadUint64()
+		if nivals > uint64(len(buf.Cur()))/16 {
+			return ErrInvalidGTIDSet
+		}
+		for j := uint64(0); j < nivals; j++ {
+			// End of an interval is exclusive in binary representation
+			start, end := buf.ReadUint64(), buf.ReadUint64()
+			if end <= start {
+				return ErrInvalidGTIDSet
+			}
+			s.addInterval(sid, GTIDInterval{Start: start, End: end - 1})
+		}
+	}
+	return nil
+}
+
+// SIDs returns a sorted list of source identifiers present in the set.
+func (s GTIDSet) SIDs() []SID {
+	sids := make([]SID, 0, len(s.sets))
+	for sid := range s.sets {
+		sids = append(sids, sid)
+	}
+	sort.Slice(sids, func(i, j int) bool {
+		return bytes.Compare(sids[i][:], sids[j][:]) < 0
+	})
+	return sids
+}
+
+// Intervals returns a list of transaction number intervals for the given
+// source identifier.
+func (s GTIDSet) Intervals(sid SID) []GTIDInterval {
+	return s.sets[sid]
+}
+
+// String returns a UUID representation of the source identifier.
+func (sid SID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], sid[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], sid[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], sid[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], sid[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], sid[10:])
+	return string(b[:])
+}
+
+// ParseGTIDSet parses a GTID set in MySQL textual form. Whitespace is ignored,
+// so that the output of @@gtid_executed can be used directly.
+// Example: 3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:7,3E11FA47-...:1-3
+func ParseGTIDSet(str string) (GTIDSet, error) {
+	var s GTIDSet
+	str = strings.Join(strings.Fields(str), "")
+	if str == "" {
+		return s, nil
+	}
+
+	for _, part := range strings.Split(str, ",") {
+		tokens := strings.Split(part, ":")
+		sid, err := ParseSID(tokens[0])
+		if err != nil {
+			return s, err
+		}
+		if len(tokens) < 2 {
+			return s, fmt.Errorf("no intervals for server UUID %q", tokens[0])
+		}
+		for _, tok := range tokens[1:] {
+			ival, err := parseGTIDInterval(tok)
+			if err != nil {
+				return s, err
+			}
+			s.addInterval(sid, ival)
+		}
+	}
+	return s, nil
+}
+
+// ParseSID parses a server UUID.
+func ParseSID(str string) (SID, error) {
+	var sid SID
+	b, err := hex.DecodeString(strings.Replace(str, "-", "", -1))
+	if err != nil || len(b) != len(sid) {
+		return sid, fmt.Errorf("invalid server UUID %q", str)
+	}
+	copy(sid[:], b)
+	return sid, nil
+}
+
+func parseGTIDInterval(str string) (GTIDInterval, error) {
+	var ival GTIDInterval
+	var err error
+	bounds := strings.SplitN(str, "-", 2)
+	ival.Start, err = strconv.ParseUint(bounds[0], 10, 64)
+	if err != nil {
+		return ival, fmt.Errorf("invalid GTID interval %q: %v", str, err)
+	}
+	ival.End = ival.Start
+	if len(bounds) == 2 {
+		ival.End, err = strconv.ParseUint(bounds[1], 10, 64)
+		if err != nil {
+			return ival, fmt.Errorf("invalid GTID interval %q: %v", str, err)
+		}
+	}
+	if ival.Start == 0 || ival.End < ival.Start {
+		return ival, fmt.Errorf("invalid GTID interval %q", str)
+	}
+	return ival, nil
+}
+
+// String returns GTID set in MySQL textual form.
+func (s GTIDSet) String() string {
+	var sb strings.Builder
+	for i, sid := range s.SIDs() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sid.String())
+		for _, ival := range s.sets[sid] {
+			sb.WriteByte(':')
+			sb.WriteString(strconv.FormatUint(ival.Start, 10))
+			if ival.End > ival.Start {
+				sb.WriteByte('-')
+				sb.WriteString(strconv.FormatUint(ival.End, 10))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// IsEmpty returns true if the set contains no transactions.
+func (s GTIDSet) IsEmpty() bool {
+	return len(s.sets) == 0
+}
+
+// Clone returns a deep copy of the set.
+func (s GTIDSet) Clone() GTIDSet {
+	c := GTIDSet{sets: make(map[SID][]GTIDInterval, len(s.sets))}
+	for sid, ivals := range s.sets {
+		c.sets[sid] = append([]GTIDInterval(nil), ivals...)
+	}
+	return c
+}
+
+// Add adds a single transaction to the set.
+func (s *GTIDSet) Add(sid SID, gno uint64) {
+	s.addInterval(sid, GTIDInterval{Start: gno, End: gno})
+}
+
+// Union adds all transactions of the other set to this one.
+func (s *GTIDSet) Union(other GTIDSet) {
+	for sid, ivals := range other.sets {
+		for _, ival := range ivals {
+			s.addInterval(sid, ival)
+		}
+	}
+}
+
+// Subtract removes all transactions of the other set from this one.
+func (s *GTIDSet) Subtract(other GTIDSet) {
+	for sid, sub := range other.sets {
+		ivals, ok := s.sets[sid]
+		if !ok {
+			continue
+		}
+		for _, cut := range sub {
+			ivals = subtractInterval(ivals, cut)
+		}
+		if len(ivals) > 0 {
+			s.sets[sid] = ivals
+		} else {
+			delete(s.sets, sid)
+		}
+	}
+}
+
+// Contains returns true if the given transaction is part of the set.
+func (s GTIDSet) Contains(sid SID, gno uint64) bool {
+	ivals := s.sets[sid]
+	i := sort.Search(len(ivals), func(i int) bool { return ivals[i].End >= gno })
+	return i < len(ivals) && ivals[i].Start <= gno
+}
+
+// ContainsSet returns true if every transaction of the other set is part of
+// this set.
+func (s GTIDSet) ContainsSet(other GTIDSet) bool {
+	for sid, sub := range other.sets {
+		ivals := s.sets[sid]
+		for _, ival := range sub {
+			i := sort.Search(len(ivals), func(i int) bool { return ivals[i].End >= ival.Start })
+			if i == len(ivals) || ivals[i].Start > ival.Start || ivals[i].End < ival.End {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Encode returns binary representation of the set, the one used by previous
+// GTIDs event and binlog dump command.
+func (s GTIDSet) Encode() []byte {
+	size := 8
+	for _, ivals := range s.sets {
+		size += 16 + 8 + len(ivals)*16
+	}
+
+	data := make([]byte, size)
+	buf := buffer.New(data)
+	buf.WriteUint64(uint64(len(s.sets)))
+	for _, sid := range s.SIDs() {
+		buf.WriteBytes(sid[:])
+		buf.WriteUint64(uint64(len(s.sets[sid])))
+		for _, ival := range s.sets[sid] {
+			// End of an interval is exclusive in binary representation
+			buf.WriteUint64(ival.Start)
+			buf.WriteUint64(ival.End + 1)
+		}
+	}
+	return data
+}
+
+// addInterval adds an interval to the list of intervals for a given source
+// while keeping the list sorted and merging overlapping and adjacent intervals.
+func (s *GTIDSet) addInterval(sid SID, ival GTIDInterval) {
+	if s.sets == nil {
+		s.sets = make(map[SID][]GTIDInterval)
+	}
+	ivals := s.sets[sid]
+	i := sort.Search(len(ivals), func(i int) bool { return ivals[i].End+1 >= ival.Start })
+	j := i
+	for j < len(ivals) && ivals[j].Start <= ival.End+1 {
+		if ivals[j].Start < ival.Start {
+			ival.Start = ivals[j].Start
+		}
+		if ivals[j].End > ival.End {
+			ival.End = ivals[j].End
+		}
+		j++
+	}
+
+	res := make([]GTIDInterval, 0, len(ivals)-(j-i)+1)
+	res = append(res, ivals[:i]...)
+	res = append(res, ival)
+	res = append(res, ivals[j:]...)
+	s.sets[sid] = res
+}
+
+func subtractInterval(ivals []GTIDInterval, cut GTIDInterval) []GTIDInterval {
+	res := make([]GTIDInterval, 0, len(ivals)+1)
+	for _, ival := range ivals {
+		if ival.End < cut.Start || ival.Start > cut.End {
+			res = append(res, ival)
+			continue
+		}
+		if ival.Start < cut.Start {
+			res = append(res, GTIDInterval{Start: ival.Start, End: cut.Start - 1})
+		}
+		if ival.End > cut.End {
+			res = append(res, GTIDInterval{Start: cut.End + 1, End: ival.End})
+		}
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/localhots/bocadillo/mysql"
+)
+
+const (
+	testUUID1 = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	testUUID2 = "57b70f4e-20d3-11e5-a393-4a63946f7eac"
+)
+
+func TestParseGTIDSet(t *testing.T) {
+	inputs := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{testUUID1 + ":1-5", testUUID1 + ":1-5"},
+		{testUUID1 + ":7:1-5", testUUID1 + ":1-5:7"},
+		{testUUID1 + ":1-5:6", testUUID1 + ":1-6"},
+		{testUUID1 + ":1-5:3-8", testUUID1 + ":1-8"},
+		{"3E11FA47-71CA-11E1-9E33-C80AA9429562:1", testUUID1 + ":1"},
+		{testUUID2 + ":1-3,\n" + testUUID1 + ":1-5", testUUID1 + ":1-5," + testUUID2 + ":1-3"},
+	}
+	for _, in := range inputs {
+		s, err := ParseGTIDSet(in.in)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", in.in, err)
+			continue
+		}
+		if s.String() != in.out {
+			t.Errorf("Expected %q to be formatted as %q, got %q", in.in, in.out, s.String())
+		}
+	}
+
+	for _, in := range []string{"foo:1", testUUID1, testUUID1 + ":0", testUUID1 + ":5-3", testUUID1 + ":a"} {
+		if _, err := ParseGTIDSet(in); err == nil {
+			t.Errorf("Expected an error parsing %q", in)
+		}
+	}
+}
+
+func TestGTIDSetOperations(t *testing.T) {
+	s := mustParseGTIDSet(t, testUUID1+":1-5:10-20")
+	sid, _ := ParseSID(testUUID1)
+
+	s.Add(sid, 6)
+	s.Union(mustParseGTIDSet(t, testUUID1+":8-9,"+testUUID2+":1"))
+	if exp := testUUID1 + ":1-6:8-20," + testUUID2 + ":1"; s.String() != exp {
+		t.Errorf("Expected %q, got %q", exp, s.String())
+	}
+
+	s.Subtract(mustParseGTIDSet(t, testUUID1+":3:15-25,"+testUUID2+":1"))
+	if exp := testUUID1 + ":1-2:4-6:8-14"; s.String() != exp {
+		t.Errorf("Expected %q, got %q", exp, s.String())
+	}
+
+	if !s.Contains(sid, 4) || s.Contains(sid, 3) || s.Contains(sid, 15) {
+		t.Errorf("Unexpected containment results for %q", s.String())
+	}
+	if !s.ContainsSet(mustParseGTIDSet(t, testUUID1+":1:9-12")) {
+		t.Errorf("Expected %q to contain a subset", s.String())
+	}
+	if s.ContainsSet(mustParseGTIDSet(t, testUUID1+":6-8")) {
+		t.Errorf("Expected %q not to contain a set with a gap", s.String())
+	}
+}
+
+func TestGTIDSetEncoding(t *testing.T) {
+	s := mustParseGTIDSet(t, testUUID1+":1-5:7,"+testUUID2+":1-3")
+	var dec GTIDSet
+	if err := dec.Decode(s.Encode()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dec.String() != s.String() {
+		t.Errorf("Expected %q, got %q", s.String(), dec.String())
+	}
+}
+
+func TestGTIDSetDecodeInvalid(t *testing.T) {
+	valid := mustParseGTIDSet(t, testUUID1+":1-5").Encode()
+	hugeSIDs := append([]byte{}, valid...)
+	mysql.EncodeUint64(hugeSIDs, 1<<62)
+	hugeIntervals := append([]byte{}, valid...)
+	mysql.EncodeUint64(hugeIntervals[8+16:], 1<<60)
+	emptyInterval := append([]byte{}, valid...)
+	mysql.EncodeUint64(emptyInterval[8+16+8+8:], 1)
+	zeroEnd := append([]byte{}, valid...)
+	mysql.EncodeUint64(zeroEnd[8+16+8+8:], 0)
+
+	tests := map[string][]byte{
+		"truncated":        valid[:len(valid)-1],
+		"too many sources": hugeSIDs,
+		"too many ranges":  hugeIntervals,
+		"empty interval":   emptyInterval,
+		"zero end":         zeroEnd,
+	}
+	for name, data := range tests {
+		var s GTIDSet
+		if err := s.Decode(data); err != ErrInvalidGTIDSet {
+			t.Errorf("Expected %s set to be invalid, got %v", name, err)
+		}
+	}
+}
+
+func mustParseGTIDSet(t *testing.T, str string) GTIDSet {
+	s, err := ParseGTIDSet(str)
+	if err != nil {
+		t.Fatalf("Failed to parse GTID set %q: %v", str, err)
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MariaDBGTID is a MariaDB global transaction identifier. Unlike MySQL, it is
+// composed of a replication domain ID, server ID and a sequence number.
+type MariaDBGTID struct {
+	DomainID       uint32
+	ServerID       uint32
+	SequenceNumber uint64
+}
+
+// MariaDBGTIDSet is a MariaDB replication position: the last transaction
+// executed in each replication domain.
+type MariaDBGTIDSet struct {
+	gtids map[uint32]MariaDBGTID
+}
+
+var (
+	// ErrInvalidMariaDBGTID is returned when MariaDB GTID cannot be parsed.
+	ErrInvalidMariaDBGTID = errors.New("MariaDB GTID is invalid")
+)
+
+// ParseMariaDBGTID parses a MariaDB GTID in textual form, e.g. 0-1-100.
+func ParseMariaDBGTID(str string) (MariaDBGTID, error) {
+	var g MariaDBGTID
+	tokens := strings.Split(str, "-")
+	if len(tokens) != 3 {
+		return g, ErrInvalidMariaDBGTID
+	}
+	domain, err := strconv.ParseUint(tokens[0], 10, 32)
+	if err != nil {
+		return g, ErrInvalidMariaDBGTID
+	}
+	server, err := strconv.ParseUint(tokens[1], 10, 32)
+	if err != nil {
+		return g, ErrInvalidMariaDBGTID
+	}
+	seq, err := strconv.ParseUint(tokens[2], 10, 64)
+	if err != nil {
+		return g, ErrInvalidMariaDBGTID
+	}
+	return MariaDBGTID{DomainID: uint32(domain), ServerID: uint32(server), SequenceNumber: seq}, nil
+}
+
+func (g MariaDBGTID) String() string {
+	return fmt.Sprintf("%d-%d-%d", g.DomainID, g.ServerID, g.SequenceNumber)
+}
+
+// ParseMariaDBGTIDSet parses a comma separated list of MariaDB GTIDs, e.g.
+// 0-1-100,1-2-5. This is the format of gtid_slave_pos and similar variables.
+func ParseMariaDBGTIDSet(str string) (MariaDBGTIDSet, error) {
+	var s MariaDBGTIDSet
+	str = strings.Join(strings.Fields(str), "")
+	if str == "" {
+		return s, nil
+	}
+
+	for _, part := range strings.Split(str, ",") {
+		g, err := ParseMariaDBGTID(part)
+		if err != nil {
+			return s, err
+		}
+		s.Update(g)
+	}
+	return s, nil
+}
+
+// Update sets given GTID as the last one executed in its domain.
+func (s *MariaDBGTIDSet) Update(g MariaDBGTID) {
+	if s.gtids == nil {
+		s.gtids = make(map[uint32]MariaDBGTID)
+	}
+	s.gtids[g.DomainID] = g
+}
+
+// GTID returns the last GTID executed in given domain.
+func (s MariaDBGTIDSet) GTID(domain uint32) (MariaDBGTID, bool) {
+	g, ok := s.gtids[domain]
+	return g, ok
+}
+
+// GTIDs returns last GTIDs of all domains ordered by domain ID.
+func (s MariaDBGTIDSet) GTIDs() []MariaDBGTID {
+	gtids := make([]MariaDBGTID, 0, len(s.gtids))
+	for _, g := range s.gtids {
+		gtids = append(gtids, g)
+	}
+	sort.Slice(gtids, func(i, j int) bool {
+		return gtids[i].DomainID < gtids[j].DomainID
+	})
+	return gtids
+}
+
+func (s MariaDBGTIDSet) String() string {
+	gtids := s.GTIDs()
+	parts := make([]string, len(gtids))
+	for i, g := range gtids {
+		parts[i] = g.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// IsEmpty returns true if the set contains no GTIDs.
+func (s MariaDBGTIDSet) IsEmpty() bool {
+	return len(s.gtids) == 0
+}
+
+// Clone returns a copy of the set.
+func (s MariaDBGTIDSet) Clone() MariaDBGTIDSet {
+	c := MariaDBGTIDSet{gtids: make(map[uint32]MariaDBGTID, len(s.gtids))}
+	for d, g := range s.gtids {
+		c.gtids[d] = g
+	}
+	return c
+}
@@ -157,6 +157,12 @@ func (b *Buffer) WriteUint32(v uint32) {
 	b.pos += 4
 }
 
+// WriteUint64 writes given uint64 value to the buffer and advances cursor by 8.
+func (b *Buffer) WriteUint64(v uint64) {
+	binary.LittleEndian.PutUint64(b.data[b.pos:], v)
+	b.pos += 8
+}
+
 // WriteStringLenEnc writes a length-encoded string to the buffer and advances
 // cursor accordingly.
 func (b *Buffer) WriteStringLenEnc(s string) {
@@ -171,6 +177,12 @@ func (b *Buffer) WriteStringEOF(s string) {
 	b.pos += copy(b.data[b.pos:], s)
 }
 
+// WriteBytes writes given slice of bytes to the buffer and advances cursor by
+// its length.
+func (b *Buffer) WriteBytes(p []byte) {
+	b.pos += copy(b.data[b.pos:], p)
+}
+
 //
 // Special types
 //
@@ -4,14 +4,13 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/juju/errors"
 	"github.com/localhots/bocadillo/mysql/driver"
 	"github.com/localhots/bocadillo/reader"
 )
@@ -21,55 +20,65 @@ func main() {
 	id := flag.Uint("id", 1000, "Server ID (arbitrary, unique)")
 	file := flag.String("file", "", "Binary log file name")
 	offset := flag.Uint("offset", 0, "Log offset in bytes")
+	gtids := flag.String("gtid", "", "Executed GTID set (used instead of file and offset)")
+	mariaGTIDs := flag.String("mariadb-gtid", "", "MariaDB GTID position (used instead of file and offset)")
+	heartbeat := flag.Duration("heartbeat", 30*time.Second, "Master heartbeat period")
+	nonBlock := flag.Bool("non-block", false, "Exit once the end of binary log is reached")
+	since := flag.String("since", "", "Start from the first transaction at or after given time (RFC3339, used instead of file and offset)")
 	flag.Parse()
 
 	validate((*dsn != ""), "Database source name is not set")
 	validate((*id != 0), "Server ID is not set")
-	validate((*file != ""), "Binary log file is not set")
+	validate((*file != "" || *gtids != "" || *mariaGTIDs != "" || *since != ""), "Binary log file or GTID set is not set")
 
-	reader, err := reader.New(*dsn, driver.Config{
-		ServerID: uint32(*id),
-		File:     *file,
-		Offset:   uint32(*offset),
-	})
+	conf := driver.Config{
+		ServerID:        uint32(*id),
+		File:            *file,
+		Offset:          uint32(*offset),
+		GTIDSet:         *gtids,
+		MariaDBGTIDSet:  *mariaGTIDs,
+		HeartbeatPeriod: *heartbeat,
+		NonBlocking:     *nonBlock,
+	}
+	if *since != "" {
+		ts, err := time.Parse(time.RFC3339, *since)
+		validate((err == nil), "Start time is invalid")
+		pos, err := reader.PositionAt(context.Background(), *dsn, conf, ts)
+		if err != nil {
+			log.Fatalf("Failed to find start position: %v", err)
+		}
+		log.Printf("Starting from %s @ %d\n", pos.File, pos.Offset)
+		conf.File, conf.Offset = pos.File, uint32(pos.Offset)
+	}
+
+	reader, err := reader.New(*dsn, conf)
 	if err != nil {
 		log.Fatalf("Failed to create reader: %v", err)
 	}
 
-	done := handleShutdown()
-	ctx := context.Background()
+	ctx := handleShutdown()
 	for {
-		ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-		defer cancel()
-		select {
-		case <-done:
-			log.Println("Closing reader")
-			err := reader.Close()
-			if err != nil {
-				log.Fatalf("Failed to close reader: %v", err)
-			}
-			return
-		default:
-			evt, err := reader.ReadEvent(ctx)
-			if err != nil {
-				if isTimeout(err) {
-					log.Println("Event read timeout")
-					continue
+		evt, err := reader.ReadEvent(ctx)
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				log.Println("Closing reader")
+				if err := reader.Close(); err != nil {
+					log.Fatalf("Failed to close reader: %v", err)
 				}
-				log.Fatalf("Failed to read event: %v", err)
+				return
 			}
+			log.Fatalf("Failed to read event: %v", err)
+		}
 
-			ts := time.Unix(int64(evt.Header.Timestamp), 0).Format(time.RFC3339)
-			log.Printf("Event received: %s %s, %d\n", evt.Header.Type.String(), ts, evt.Header.NextOffset)
+		ts := time.Unix(int64(evt.Header.Timestamp), 0).Format(time.RFC3339)
+		log.Printf("Event received: %s %s, %d\n", evt.Header.Type.String(), ts, evt.Header.NextOffset)
 
-			if evt.Table != nil {
-				_, err := evt.DecodeRows()
-				if err != nil {
-					log.Fatalf("Failed to parse rows event: %v", err)
-				}
+		if evt.Table != nil {
+			_, err := evt.DecodeRows()
+			if err != nil {
+				log.Fatalf("Failed to parse rows event: %v", err)
 			}
 		}
-		cancel()
 	}
 }
 
@@ -81,26 +90,16 @@ func validate(cond bool, msg string) {
 	}
 }
 
-func handleShutdown() <-chan struct{} {
+// handleShutdown returns a context that is canceled once shutdown is
+// requested.
+func handleShutdown() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
 	sig := make(chan os.Signal, 1)
-	done := make(chan struct{})
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sig
 		log.Println("Shutdown requested")
-		close(done)
+		cancel()
 	}()
-	return done
-}
-
-func isTimeout(err error) bool {
-	if err == nil {
-		return false
-	}
-	err = errors.Cause(err)
-	if err == context.DeadlineExceeded || err == context.Canceled {
-		return true
-	}
-	ne, ok := err.(*net.OpError)
-	return ok && ne.Timeout()
+	return ctx
 }
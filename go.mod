@@ -4,6 +4,7 @@ require (
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/google/go-cmp v0.3.1
 	github.com/juju/errors v0.0.0-20190930114154-d42613fe1ab9
+	github.com/klauspost/compress v1.12.3
 )
 
 go 1.13
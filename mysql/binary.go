@@ -192,7 +192,7 @@ func DecodeVarLen64BigEndian(data []byte) uint64 {
 func DecodeStringNullTerm(data []byte) []byte {
 	for i, c := range data {
 		if c == 0x00 {
-			s := make([]byte, i+1)
+			s := make([]byte, i)
 			copy(s, data[:i])
 			return s
 		}
@@ -16,12 +16,21 @@ type Decimal struct {
 	str string
 }
 
+const digitsPerInteger int = 9
+
+var compressedBytes = [...]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// DecimalSize returns the length of a binary encoded decimal value with given
+// precision and number of decimals.
+func DecimalSize(precision int, decimals int) int {
+	integral := precision - decimals
+	return integral/digitsPerInteger*4 + compressedBytes[integral%digitsPerInteger] +
+		decimals/digitsPerInteger*4 + compressedBytes[decimals%digitsPerInteger]
+}
+
 // DecodeDecimal decodes a decimal value.
 // Implementation borrowed from https://github.com/siddontang/go-mysql/
 func DecodeDecimal(data []byte, precision int, decimals int) (Decimal, int) {
-	const digitsPerInteger int = 9
-	var compressedBytes = [...]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
-
 	decodeDecimalDecompressValue := func(compIndx int, data []byte, mask uint8) (size int, value uint32) {
 		size = compressedBytes[compIndx]
 		databuff := make([]byte, size)
@@ -39,8 +48,7 @@ func DecodeDecimal(data []byte, precision int, decimals int) (Decimal, int) {
 	compIntegral := integral - (uncompIntegral * digitsPerInteger)
 	compFractional := decimals - (uncompFractional * digitsPerInteger)
 
-	binSize := uncompIntegral*4 + compressedBytes[compIntegral] +
-		uncompFractional*4 + compressedBytes[compFractional]
+	binSize := DecimalSize(precision, decimals)
 
 	buf := make([]byte, binSize)
 	copy(buf, data[:binSize])
@@ -2,17 +2,48 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"github.com/localhots/bocadillo/binlog"
 	"github.com/localhots/bocadillo/buffer"
 	"github.com/localhots/bocadillo/mysql/driver/internal/mysql"
 )
 
 // Conn is a connection used to issue a binlog dump command.
 type Conn struct {
-	conn *mysql.ExtendedConn
-	conf Config
+	conn   packetConn
+	conf   Config
+	flavor binlog.Flavor
+
+	// checksum is true if events sent over the connection carry checksums
+	checksum bool
+
+	// Semi-synchronous replication state
+	semiSync     bool
+	ackRequested bool
+
+	// First packet of the dump is read when the dump is started, it is
+	// returned by the following ReadPacket call
+	first    []byte
+	firstErr error
+}
+
+// packetConn is a low level connection that exchanges packets with the
+// server. It is implemented by the extended driver connection.
+type packetConn interface {
+	Exec(query string) error
+	GetSystemVar(name string) (string, error)
+	ReadPacket(ctx context.Context) ([]byte, error)
+	WritePacket(p []byte) error
+	ReadResultOK() error
+	HandleErrorPacket(data []byte) error
+	ResetSequence()
+	SyncSequence()
+	Close() error
 }
 
 // Config contains all the details necessary to establish a replica connection.
@@ -28,17 +59,69 @@ type Config struct {
 	// Hostname along with server ID is used to identify the replica server
 	// connection.
 	Hostname string
+	// GTIDSet is a set of transactions already executed by the replica, in
+	// MySQL textual form (e.g. 3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5).
+	// When set, dump is started with GTID positioning and the server would
+	// send every transaction that is not part of the set. File and offset are
+	// ignored in this case.
+	GTIDSet string
+	// MariaDBGTIDSet is a MariaDB replication position, the last transaction
+	// executed in each replication domain (e.g. 0-1-100,1-2-5). When set, dump
+	// is started with MariaDB GTID positioning. File and offset are ignored
+	// in this case.
+	MariaDBGTIDSet string
+	// VerifyChecksum keeps event checksums enabled for this connection using
+	// the algorithm configured on the server, so that each event could be
+	// verified by the receiver. Checksums are disabled otherwise.
+	VerifyChecksum bool
+	// SemiSync makes the connection act as a semi-synchronous replica. Master
+	// would wait for an acknowledgement of transactions it marks so.
+	SemiSync bool
+	// HeartbeatPeriod is the interval at which master sends heartbeat events
+	// while there are no new events to send. Heartbeats are disabled if zero.
+	HeartbeatPeriod time.Duration
+	// NonBlocking makes master stop the dump once it reaches the end of the
+	// binary log instead of waiting for new events. Reading past the last
+	// event returns io.EOF.
+	NonBlocking bool
+	// SkipValidation disables the check of master settings (binary logging
+	// enabled, ROW format, distinct server ID) made before a reader is
+	// created. The check needs a separate connection.
+	SkipValidation bool
 }
 
 const (
 	// Commands
-	comRegisterSlave byte = 21
-	comBinlogDump    byte = 18
+	comRegisterSlave  byte = 21
+	comBinlogDump     byte = 18
+	comBinlogDumpGTID byte = 30
+
+	// Binlog dump flags
+	dumpFlagNonBlock         uint16 = 0x01
+	dumpFlagSendAnnotateRows uint16 = 0x02
+	dumpFlagThroughGTID      uint16 = 0x04
+
+	// mariaDBCapabilityGTID makes MariaDB server send GTID, annotate rows and
+	// other MariaDB specific events instead of their MySQL compatible
+	// replacements.
+	mariaDBCapabilityGTID = 4
 
 	// Result codes
 	resultOK  byte = 0x00
 	resultEOF byte = 0xFE
 	resultERR byte = 0xFF
+
+	// Semi-synchronous replication packets start with a magic byte. Event
+	// packets carry a flag that tells if the master waits for an
+	// acknowledgement
+	semiSyncIndicator  byte = 0xEF
+	semiSyncAckRequest byte = 0x01
+)
+
+var (
+	// ErrSemiSyncUnsupported is returned when semi-synchronous replication
+	// is requested but master doesn't have it installed.
+	ErrSemiSyncUnsupported = errors.New("Semi-synchronous replication is not supported by master")
 )
 
 // Connect esablishes a new database connection. It is a go-sql-driver
@@ -73,6 +156,12 @@ func Connect(dsn string, conf Config) (*Conn, error) {
 
 // ReadPacket reads next packet from the server and peeks at the status byte.
 func (c *Conn) ReadPacket(ctx context.Context) ([]byte, error) {
+	if c.first != nil || c.firstErr != nil {
+		data, err := c.first, c.firstErr
+		c.first, c.firstErr = nil, nil
+		return data, err
+	}
+
 	data, err := c.conn.ReadPacket(ctx)
 	if err != nil {
 		return nil, err
@@ -80,11 +169,19 @@ func (c *Conn) ReadPacket(ctx context.Context) ([]byte, error) {
 
 	switch data[0] {
 	case resultOK:
+		if c.semiSync {
+			if len(data) < 3 || data[1] != semiSyncIndicator {
+				return nil, fmt.Errorf("unexpected semi-sync header: %x", data[1:])
+			}
+			c.ackRequested = data[2]&semiSyncAckRequest > 0
+			return data[3:], nil
+		}
 		return data[1:], nil
 	case resultERR:
 		return nil, c.conn.HandleErrorPacket(data)
 	case resultEOF:
-		return nil, nil
+		// Non-blocking dump has reached the end of the binary log
+		return nil, io.EOF
 	default:
 		return nil, fmt.Errorf("unexpected header: %x", data[0])
 	}
@@ -113,23 +210,191 @@ func (c *Conn) RegisterSlave() error {
 
 // StartBinlogDump issues a BINLOG_DUMP command to master.
 // Spec: https://dev.mysql.com/doc/internals/en/com-binlog-dump.html
-// TODO: https://dev.mysql.com/doc/internals/en/com-binlog-dump-gtid.html
 func (c *Conn) StartBinlogDump() error {
 	c.conn.ResetSequence()
 
 	buf := buffer.NewCommandBuffer(1 + 4 + 2 + 4 + len(c.conf.File))
 	buf.WriteByte(comBinlogDump)
 	buf.WriteUint32(uint32(c.conf.Offset))
-	buf.Skip(2) // Flags
+	buf.WriteUint16(c.dumpFlags())
 	buf.WriteUint32(c.conf.ServerID)
 	buf.WriteStringEOF(c.conf.File)
 
-	return c.runCmd(buf.Bytes())
+	return c.startDump(buf.Bytes())
+}
+
+// StartBinlogDumpGTID issues a BINLOG_DUMP_GTID command to master. Binary log
+// file name is left empty so that the server could find the first transaction
+// that is missing from the GTID set on its own.
+// Spec: https://dev.mysql.com/doc/internals/en/com-binlog-dump-gtid.html
+func (c *Conn) StartBinlogDumpGTID() error {
+	data, err := c.binlogDumpGTIDCommand()
+	if err != nil {
+		return err
+	}
+
+	c.conn.ResetSequence()
+	return c.startDump(data)
+}
+
+// binlogDumpGTIDCommand builds a BINLOG_DUMP_GTID command packet.
+func (c *Conn) binlogDumpGTIDCommand() ([]byte, error) {
+	gtids, err := binlog.ParseGTIDSet(c.conf.GTIDSet)
+	if err != nil {
+		return nil, err
+	}
+	gtidData := gtids.Encode()
+
+	buf := buffer.NewCommandBuffer(1 + 2 + 4 + 4 + 8 + 4 + len(gtidData))
+	buf.WriteByte(comBinlogDumpGTID)
+	buf.WriteUint16(c.dumpFlags() | dumpFlagThroughGTID)
+	buf.WriteUint32(c.conf.ServerID)
+	buf.WriteUint32(0) // File name length
+	buf.WriteUint64(4) // Offset
+	buf.WriteUint32(uint32(len(gtidData)))
+	buf.WriteBytes(gtidData)
+
+	return buf.Bytes(), nil
+}
+
+// StartBinlogDumpMariaDBGTID issues a BINLOG_DUMP command to a MariaDB master
+// with GTID positioning. Position is passed as a user variable and file name
+// is left empty, the server reports it in a fake rotate event that is the
+// first event of the dump.
+// Spec: https://mariadb.com/kb/en/com_binlog_dump/
+func (c *Conn) StartBinlogDumpMariaDBGTID() error {
+	gtids, err := binlog.ParseMariaDBGTIDSet(c.conf.MariaDBGTIDSet)
+	if err != nil {
+		return err
+	}
+	if err := c.SetVar("@slave_connect_state", gtids.String()); err != nil {
+		return err
+	}
+	if err := c.conn.Exec("SET @slave_gtid_strict_mode=0"); err != nil {
+		return err
+	}
+	if err := c.conn.Exec("SET @slave_gtid_ignore_duplicates=0"); err != nil {
+		return err
+	}
+
+	conf := c.conf
+	c.conf.File, c.conf.Offset = "", 4
+	defer func() { c.conf = conf }()
+	return c.StartBinlogDump()
+}
+
+// Flavor returns the flavor of the server, it is detected from the server
+// version.
+func (c *Conn) Flavor() (binlog.Flavor, error) {
+	if c.flavor == "" {
+		version, err := c.conn.GetSystemVar("version")
+		if err != nil {
+			return "", err
+		}
+		c.flavor = binlog.DetectFlavor(version)
+	}
+	return c.flavor, nil
+}
+
+// EnableMariaDBCapability lets MariaDB master know that the replica
+// understands MariaDB specific events. It must be called before starting a
+// binlog dump from a MariaDB server.
+func (c *Conn) EnableMariaDBCapability() error {
+	return c.conn.Exec(fmt.Sprintf("SET @mariadb_slave_capability=%d", mariaDBCapabilityGTID))
+}
+
+// EnableSemiSync lets master know that this connection is a semi-synchronous
+// replica. Master must have semi-synchronous replication installed, packets
+// would be prefixed with a header otherwise.
+func (c *Conn) EnableSemiSync() error {
+	_, err := c.conn.GetSystemVar("rpl_semi_sync_master_enabled")
+	if IsServerError(err) {
+		// Variable was renamed in MySQL 8.0.26
+		_, err = c.conn.GetSystemVar("rpl_semi_sync_source_enabled")
+	}
+	if IsServerError(err) {
+		return ErrSemiSyncUnsupported
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.conn.Exec("SET @rpl_semi_sync_slave=1"); err != nil {
+		return err
+	}
+	if err := c.conn.Exec("SET @rpl_semi_sync_replica=1"); err != nil {
+		return err
+	}
+	c.semiSync = true
+	return nil
+}
+
+// AckRequested returns true if master waits for an acknowledgement of the last
+// packet read.
+func (c *Conn) AckRequested() bool {
+	return c.ackRequested
+}
+
+// SemiSyncAck acknowledges that events up to given position were received.
+// It must not be called concurrently with ReadPacket.
+func (c *Conn) SemiSyncAck(pos binlog.Position) error {
+	if !c.semiSync {
+		return ErrSemiSyncUnsupported
+	}
+
+	// Acknowledgement is sent outside of the dump packets sequence
+	c.conn.ResetSequence()
+	defer c.conn.SyncSequence()
+
+	buf := buffer.NewCommandBuffer(1 + 8 + len(pos.File))
+	buf.WriteByte(semiSyncIndicator)
+	buf.WriteUint64(pos.Offset)
+	buf.WriteStringEOF(pos.File)
+	if err := c.conn.WritePacket(buf.Bytes()); err != nil {
+		return err
+	}
+	c.ackRequested = false
+	return nil
+}
+
+// SetHeartbeatPeriod makes master send heartbeat events every given period
+// while there are no new events to send.
+func (c *Conn) SetHeartbeatPeriod(d time.Duration) error {
+	// Period is set in nanoseconds, variable was renamed in MySQL 8.0.26
+	if err := c.conn.Exec(fmt.Sprintf("SET @master_heartbeat_period=%d", d.Nanoseconds())); err != nil {
+		return err
+	}
+	return c.conn.Exec(fmt.Sprintf("SET @source_heartbeat_period=%d", d.Nanoseconds()))
 }
 
 // DisableChecksum disables CRC32 checksums for this connection.
 func (c *Conn) DisableChecksum() error {
-	return c.SetVar("@master_binlog_checksum", "NONE")
+	if err := c.SetVar("@master_binlog_checksum", "NONE"); err != nil {
+		return err
+	}
+	c.checksum = false
+	return nil
+}
+
+// EnableChecksum enables checksums for this connection using the algorithm
+// configured on the server.
+func (c *Conn) EnableChecksum() error {
+	alg, err := c.conn.GetSystemVar("global.binlog_checksum")
+	if err != nil {
+		return err
+	}
+	if err := c.SetVar("@master_binlog_checksum", alg); err != nil {
+		return err
+	}
+	c.checksum = alg != "NONE"
+	return nil
+}
+
+// Checksum returns true if events sent over this connection carry checksums.
+// Unlike other events, the fake rotate event that starts a dump has no format
+// description event to tell it.
+func (c *Conn) Checksum() bool {
+	return c.checksum
 }
 
 // SetVar assigns a new value to the given variable.
@@ -142,6 +407,40 @@ func (c *Conn) Close() error {
 	return c.conn.Close()
 }
 
+// IsServerError returns true if the error was reported by the server, as
+// opposed to a connection failure.
+func IsServerError(err error) bool {
+	_, ok := err.(*mysql.MySQLError)
+	return ok
+}
+
+func (c *Conn) dumpFlags() uint16 {
+	var flags uint16
+	if c.conf.NonBlocking {
+		flags |= dumpFlagNonBlock
+	}
+	if c.flavor == binlog.FlavorMariaDB {
+		flags |= dumpFlagSendAnnotateRows
+	}
+	return flags
+}
+
+// startDump issues a binlog dump command. Server doesn't acknowledge the
+// command, it either reports an error or starts sending events right away
+// beginning with a fake rotate event. The first packet is read to tell one
+// from another.
+func (c *Conn) startDump(data []byte) error {
+	if err := c.conn.WritePacket(data); err != nil {
+		return err
+	}
+	first, err := c.ReadPacket(context.Background())
+	if err != nil && err != io.EOF {
+		return err
+	}
+	c.first, c.firstErr = first, err
+	return nil
+}
+
 func (c *Conn) runCmd(data []byte) error {
 	err := c.conn.WritePacket(data)
 	if err != nil {
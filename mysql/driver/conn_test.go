@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+)
+
+func TestBinlogDumpGTIDCommand(t *testing.T) {
+	c := &Conn{conf: Config{
+		ServerID:    1000,
+		GTIDSet:     "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5",
+		NonBlocking: true,
+	}}
+	data, err := c.binlogDumpGTIDCommand()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := []byte{
+		30,         // Command
+		0x05, 0x00, // Flags: non-blocking, through GTID
+		0xE8, 0x03, 0x00, 0x00, // Server ID
+		0x00, 0x00, 0x00, 0x00, // File name length
+		0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Offset
+		0x30, 0x00, 0x00, 0x00, // GTID set length
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number of SIDs
+		0x3E, 0x11, 0xFA, 0x47, 0x71, 0xCA, 0x11, 0xE1,
+		0x9E, 0x33, 0xC8, 0x0A, 0xA9, 0x42, 0x95, 0x62, // SID
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number of intervals
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Start
+		0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // End, exclusive
+	}
+	// Packet header is filled in when the packet is written
+	if !bytes.Equal(data[4:], exp) {
+		t.Errorf("Expected command\n%x\ngot\n%x", exp, data[4:])
+	}
+}
+
+func TestReadPacketSemiSync(t *testing.T) {
+	conn := &testConn{packets: [][]byte{
+		{0x00, 0xEF, 0x01, 'a', 'c', 'k'},
+		{0x00, 0xEF, 0x00, 'n', 'o'},
+		{0x00, 'n', 'o'},
+	}}
+	c := &Conn{conn: conn, semiSync: true}
+	ctx := context.Background()
+
+	data, err := c.ReadPacket(ctx)
+	if err != nil || string(data) != "ack" {
+		t.Fatalf("Expected %q, got %q (%v)", "ack", data, err)
+	}
+	if !c.AckRequested() {
+		t.Error("Expected acknowledgement to be requested")
+	}
+
+	pos := binlog.Position{File: "mysql-bin.000001", Offset: 1000}
+	if err := c.SemiSyncAck(pos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := append([]byte{0xEF, 0xE8, 0x03, 0, 0, 0, 0, 0, 0}, pos.File...)
+	if len(conn.written) != 1 || !bytes.Equal(conn.written[0][4:], exp) {
+		t.Errorf("Expected acknowledgement %x, got %x", exp, conn.written)
+	}
+	if c.AckRequested() {
+		t.Error("Expected acknowledgement request to be reset")
+	}
+
+	data, err = c.ReadPacket(ctx)
+	if err != nil || string(data) != "no" {
+		t.Fatalf("Expected %q, got %q (%v)", "no", data, err)
+	}
+	if c.AckRequested() {
+		t.Error("Expected acknowledgement not to be requested")
+	}
+
+	// Packet without a semi-sync header is unexpected
+	if _, err := c.ReadPacket(ctx); err == nil {
+		t.Error("Expected an error reading a packet without a header")
+	}
+}
+
+func TestReadPacketNonBlockingEOF(t *testing.T) {
+	conn := &testConn{packets: [][]byte{
+		{0x00, 'e', 'v', 't'},
+		{0xFE, 0x00, 0x00, 0x02, 0x00},
+	}}
+	c := &Conn{conn: conn, conf: Config{NonBlocking: true}}
+	if flags := c.dumpFlags(); flags&dumpFlagNonBlock == 0 {
+		t.Errorf("Expected non-blocking flag to be set, got %x", flags)
+	}
+
+	ctx := context.Background()
+	if data, err := c.ReadPacket(ctx); err != nil || string(data) != "evt" {
+		t.Fatalf("Expected %q, got %q (%v)", "evt", data, err)
+	}
+	// Master reports the end of the binary log with an EOF packet
+	if data, err := c.ReadPacket(ctx); err != io.EOF || data != nil {
+		t.Errorf("Expected %v, got %q (%v)", io.EOF, data, err)
+	}
+}
+
+// testConn returns given packets one by one and records written ones.
+type testConn struct {
+	packets [][]byte
+	written [][]byte
+}
+
+func (c *testConn) ReadPacket(ctx context.Context) ([]byte, error) {
+	if len(c.packets) == 0 {
+		return nil, errors.New("no more packets")
+	}
+	data := c.packets[0]
+	c.packets = c.packets[1:]
+	return data, nil
+}
+
+func (c *testConn) WritePacket(p []byte) error {
+	c.written = append(c.written, p)
+	return nil
+}
+
+func (c *testConn) HandleErrorPacket(data []byte) error {
+	return errors.New(string(data[1:]))
+}
+
+func (c *testConn) Exec(query string) error {
+	return nil
+}
+
+func (c *testConn) GetSystemVar(name string) (string, error) {
+	return "", nil
+}
+
+func (c *testConn) ReadResultOK() error {
+	return nil
+}
+
+func (c *testConn) ResetSequence() {}
+
+func (c *testConn) SyncSequence() {}
+
+func (c *testConn) Close() error {
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
+	"sync"
 	"time"
 )
 
@@ -17,17 +18,29 @@ func ExtendConn(conn driver.Conn) (*ExtendedConn, error) {
 		return nil, errors.New("Invalid connection")
 	}
 
-	return &ExtendedConn{mc}, nil
+	return &ExtendedConn{mysqlConn: mc}, nil
 }
 
 // ExtendedConn provides access to internal packet functions.
 type ExtendedConn struct {
 	*mysqlConn
+
+	// Contexts of blocking reads are passed to a goroutine that interrupts
+	// the read once its context is canceled. Reads report when they are over
+	watchOnce sync.Once
+	watch     chan context.Context
+	finished  chan struct{}
 }
 
 // Close ...
 func (c *ExtendedConn) Close() error {
 	c.buf.length = 0
+	// Watcher is never started once the connection is closed
+	c.watchOnce.Do(func() {})
+	if c.watch != nil {
+		close(c.watch)
+		c.watch = nil
+	}
 	return c.mysqlConn.Close()
 }
 
@@ -36,6 +49,15 @@ func (c *ExtendedConn) Exec(query string) error {
 	return c.exec(query)
 }
 
+// GetSystemVar returns the value of the given system variable.
+func (c *ExtendedConn) GetSystemVar(name string) (string, error) {
+	val, err := c.getSystemVar(name)
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
 // ReadPacket reads a packet from the connection.
 func (c *ExtendedConn) ReadPacket(ctx context.Context) ([]byte, error) {
 	if dl, ok := ctx.Deadline(); ok {
@@ -46,9 +68,46 @@ func (c *ExtendedConn) ReadPacket(ctx context.Context) ([]byte, error) {
 		c.buf.timeout = dur
 	} else {
 		c.buf.timeout = 0
+		// Deadline could be left over from a canceled read
+		if err := c.netConn.SetReadDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.Done() != nil {
+		// Blocking read is interrupted once the context is canceled. Reset
+		// must not outlive the call, otherwise it would interrupt the next
+		// read
+		c.watchOnce.Do(func() {
+			c.watch = make(chan context.Context)
+			c.finished = make(chan struct{})
+			go c.watchCancel(c.watch, c.finished)
+		})
+		if c.watch == nil {
+			return nil, ErrInvalidConn
+		}
+		c.watch <- ctx
+		defer func() { c.finished <- struct{}{} }()
+	}
+
+	data, err := c.readPacket()
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
+	return data, err
+}
 
-	return c.readPacket()
+// watchCancel interrupts blocking reads once their contexts are canceled. It
+// runs until the connection is closed.
+func (c *ExtendedConn) watchCancel(watch <-chan context.Context, finished <-chan struct{}) {
+	for ctx := range watch {
+		select {
+		case <-ctx.Done():
+			c.netConn.SetReadDeadline(time.Now())
+			<-finished
+		case <-finished:
+		}
+	}
 }
 
 // WritePacket writes a packet to the connection.
@@ -70,3 +129,9 @@ func (c *ExtendedConn) HandleErrorPacket(data []byte) error {
 func (c *ExtendedConn) ResetSequence() {
 	c.sequence = 0
 }
+
+// SyncSequence makes the connection accept sequence number of the next packet
+// whatever it is. Used when packets are written in the middle of a stream.
+func (c *ExtendedConn) SyncSequence() {
+	c.syncSequence = true
+}
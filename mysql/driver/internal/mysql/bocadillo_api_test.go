@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExtendedConnReadPacketCancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	c := &ExtendedConn{mysqlConn: &mysqlConn{
+		buf:     newBuffer(client),
+		netConn: client,
+		closech: make(chan struct{}),
+	}}
+	c.SyncSequence()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.Write([]byte{0x01, 0x00, 0x00, 0x00, 0xff})
+	if data, err := c.ReadPacket(ctx); err != nil || len(data) != 1 || data[0] != 0xff {
+		t.Fatalf("Expected packet to be read, got %v (%v)", data, err)
+	}
+
+	// Read is blocked until the context is canceled
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.ReadPacket(ctx); err != context.Canceled {
+		t.Fatalf("Expected error %v, got %v", context.Canceled, err)
+	}
+
+	// Connection remains usable after cancelation
+	go server.Write([]byte{0x01, 0x00, 0x00, 0x01, 0xfe})
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if data, err := c.ReadPacket(ctx); err != nil || len(data) != 1 || data[0] != 0xfe {
+		t.Fatalf("Expected packet to be read, got %v (%v)", data, err)
+	}
+}
@@ -43,6 +43,10 @@ type mysqlConn struct {
 	sequence         uint8
 	parseTime        bool
 
+	// syncSequence makes the connection accept sequence number of the next
+	// packet as is
+	syncSequence bool
+
 	// for context support (Go 1.8+)
 	watching bool
 	watcher  chan<- mysqlContext
@@ -0,0 +1,311 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/juju/errors"
+)
+
+// JSONDiffOperation is an operation of a partial JSON update.
+type JSONDiffOperation byte
+
+// JSONDiff describes a single modification of a JSON document.
+type JSONDiff struct {
+	Op JSONDiffOperation
+	// Path is a MySQL JSON path of the modified value, e.g. $.a[1]
+	Path string
+	// Value is a JSON encoded new value, it is nil for remove operations.
+	Value []byte
+}
+
+// Partial JSON update operations.
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/classJson__diff.html
+const (
+	// JSONDiffReplace replaces the value at given path.
+	JSONDiffReplace JSONDiffOperation = 0
+	// JSONDiffInsert adds a new object member or array element.
+	JSONDiffInsert JSONDiffOperation = 1
+	// JSONDiffRemove removes the value at given path.
+	JSONDiffRemove JSONDiffOperation = 2
+)
+
+type jsonPathLeg struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// DecodeJSONDiff decodes a list of partial JSON update operations. Each
+// operation consists of an operation byte, length-encoded path and, unless
+// it's a removal, length-encoded binary JSON value.
+func DecodeJSONDiff(data []byte) (diffs []JSONDiff, err error) {
+	defer func() {
+		// Length-encoded strings are not bounds checked
+		if recover() != nil {
+			diffs, err = nil, errors.New("json diff is truncated")
+		}
+	}()
+
+	for len(data) > 0 {
+		d := JSONDiff{Op: JSONDiffOperation(data[0])}
+		if d.Op > JSONDiffRemove {
+			return nil, errors.Errorf("invalid json diff operation %d", d.Op)
+		}
+		data = data[1:]
+
+		path, n := DecodeStringLenEnc(data)
+		d.Path = string(path)
+		data = data[n:]
+
+		if d.Op != JSONDiffRemove {
+			val, n := DecodeStringLenEnc(data)
+			v, err := DecodeJSON(val)
+			if err != nil {
+				return nil, errors.Annotate(err, "decode json diff value")
+			}
+			d.Value = v
+			data = data[n:]
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// ApplyJSONDiff applies given operations to a JSON document and returns the
+// modified document.
+func ApplyJSONDiff(doc []byte, diffs []JSONDiff) ([]byte, error) {
+	root, err := unmarshalJSON(doc)
+	if err != nil {
+		return nil, errors.Annotate(err, "decode json document")
+	}
+	for _, d := range diffs {
+		legs, err := parseJSONPath(d.Path)
+		if err != nil {
+			return nil, err
+		}
+		var val interface{}
+		if d.Op != JSONDiffRemove {
+			if val, err = unmarshalJSON(d.Value); err != nil {
+				return nil, errors.Annotate(err, "decode json diff value")
+			}
+		}
+		if root, err = applyJSONDiff(root, legs, d.Op, val); err != nil {
+			return nil, errors.Annotatef(err, "apply json diff at %s", d.Path)
+		}
+	}
+	return encodeJSON(root)
+}
+
+func applyJSONDiff(node interface{}, legs []jsonPathLeg, op JSONDiffOperation, val interface{}) (interface{}, error) {
+	if len(legs) == 0 {
+		if op != JSONDiffReplace {
+			return nil, errors.New("document root can only be replaced")
+		}
+		return val, nil
+	}
+
+	leg := legs[0]
+	switch tnode := node.(type) {
+	case map[string]interface{}:
+		if leg.isIndex {
+			return nil, errors.New("array index used on an object")
+		}
+		child, ok := tnode[leg.key]
+		if len(legs) > 1 {
+			if !ok {
+				return nil, errors.Errorf("member %q not found", leg.key)
+			}
+			child, err := applyJSONDiff(child, legs[1:], op, val)
+			if err != nil {
+				return nil, err
+			}
+			tnode[leg.key] = child
+			return tnode, nil
+		}
+		switch op {
+		case JSONDiffRemove:
+			delete(tnode, leg.key)
+		case JSONDiffInsert:
+			if !ok {
+				tnode[leg.key] = val
+			}
+		default:
+			tnode[leg.key] = val
+		}
+		return tnode, nil
+
+	case []interface{}:
+		if !leg.isIndex {
+			return nil, errors.New("member name used on an array")
+		}
+		i := leg.index
+		if len(legs) > 1 {
+			if i >= len(tnode) {
+				return nil, errors.Errorf("index %d out of range", i)
+			}
+			child, err := applyJSONDiff(tnode[i], legs[1:], op, val)
+			if err != nil {
+				return nil, err
+			}
+			tnode[i] = child
+			return tnode, nil
+		}
+		switch op {
+		case JSONDiffRemove:
+			if i < len(tnode) {
+				tnode = append(tnode[:i], tnode[i+1:]...)
+			}
+		case JSONDiffInsert:
+			if i >= len(tnode) {
+				tnode = append(tnode, val)
+			} else {
+				tnode = append(tnode[:i], append([]interface{}{val}, tnode[i:]...)...)
+			}
+		default:
+			if i >= len(tnode) {
+				return nil, errors.Errorf("index %d out of range", i)
+			}
+			tnode[i] = val
+		}
+		return tnode, nil
+
+	default:
+		return nil, errors.New("path points inside a scalar value")
+	}
+}
+
+// parseJSONPath parses a path that consists of member names and array indexes.
+// Wildcards are not supported because they are never used in binary log.
+func parseJSONPath(path string) ([]jsonPathLeg, error) {
+	if len(path) == 0 || path[0] != '$' {
+		return nil, errors.Errorf("invalid json path %q", path)
+	}
+
+	var legs []jsonPathLeg
+	for i := 1; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '"' {
+				// Quoted member name, find closing quote skipping escaped
+				// characters
+				j := i + 1
+				for j < len(path) && path[j] != '"' {
+					if path[j] == '\\' {
+						j++
+					}
+					j++
+				}
+				if j >= len(path) {
+					return nil, errors.Errorf("invalid json path %q", path)
+				}
+				key, err := strconv.Unquote(path[i : j+1])
+				if err != nil {
+					return nil, errors.Errorf("invalid json path %q", path)
+				}
+				legs = append(legs, jsonPathLeg{key: key})
+				i = j + 1
+				continue
+			}
+			j := i
+			for j < len(path) && path[j] != '.' && path[j] != '[' {
+				j++
+			}
+			if j == i {
+				return nil, errors.Errorf("invalid json path %q", path)
+			}
+			legs = append(legs, jsonPathLeg{key: path[i:j]})
+			i = j
+		case '[':
+			j := i + 1
+			for j < len(path) && path[j] != ']' {
+				j++
+			}
+			if j >= len(path) {
+				return nil, errors.Errorf("invalid json path %q", path)
+			}
+			idx, err := strconv.Atoi(path[i+1 : j])
+			if err != nil || idx < 0 {
+				return nil, errors.Errorf("invalid json path %q", path)
+			}
+			legs = append(legs, jsonPathLeg{index: idx, isIndex: true})
+			i = j + 1
+		default:
+			return nil, errors.Errorf("invalid json path %q", path)
+		}
+	}
+	return legs, nil
+}
+
+// unmarshalJSON decodes JSON preserving numbers as they are.
+func unmarshalJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// encodeJSON encodes a JSON value the way MySQL renders it: object keys are
+// ordered by length first and then alphabetically, HTML characters are not
+// escaped.
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(tv))
+		for k := range tv {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, tv[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, val := range tv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, val); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		// Encoder terminates each value with a newline
+		buf.Truncate(buf.Len() - 1)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestDecodeJSONDiff(t *testing.T) {
+	data := []byte{
+		byte(JSONDiffReplace), 3, '$', '.', 'a', 2, jsonLiteral, jsonTrue,
+		byte(JSONDiffRemove), 4, '$', '[', '0', ']',
+	}
+	diffs, err := DecodeJSONDiff(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 diffs, got %d", len(diffs))
+	}
+	if d := diffs[0]; d.Op != JSONDiffReplace || d.Path != "$.a" || string(d.Value) != "true" {
+		t.Errorf("Unexpected first diff: %+v", d)
+	}
+	if d := diffs[1]; d.Op != JSONDiffRemove || d.Path != "$[0]" || d.Value != nil {
+		t.Errorf("Unexpected second diff: %+v", d)
+	}
+
+	if _, err := DecodeJSONDiff(data[:6]); err == nil {
+		t.Error("Expected an error for truncated diff")
+	}
+}
+
+func TestApplyJSONDiff(t *testing.T) {
+	testcases := []struct {
+		doc   string
+		diffs []JSONDiff
+		exp   string
+	}{
+		{`{"a":1}`, []JSONDiff{{Op: JSONDiffReplace, Path: "$.a", Value: []byte(`2`)}}, `{"a":2}`},
+		{`{"a":1}`, []JSONDiff{{Op: JSONDiffInsert, Path: "$.b", Value: []byte(`[]`)}}, `{"a":1,"b":[]}`},
+		{`{"a":1,"b":2}`, []JSONDiff{{Op: JSONDiffRemove, Path: "$.b"}}, `{"a":1}`},
+		{`{"a b":[1,3]}`, []JSONDiff{{Op: JSONDiffInsert, Path: `$."a b"[1]`, Value: []byte(`2`)}}, `{"a b":[1,2,3]}`},
+		{`[1,[2,3]]`, []JSONDiff{{Op: JSONDiffRemove, Path: "$[1][0]"}}, `[1,[3]]`},
+		{`[1]`, []JSONDiff{
+			{Op: JSONDiffInsert, Path: "$[5]", Value: []byte(`"x"`)},
+			{Op: JSONDiffReplace, Path: "$[0]", Value: []byte(`12345678901234567890`)},
+		}, `[12345678901234567890,"x"]`},
+		{`{"bb":1,"a":2}`, []JSONDiff{{Op: JSONDiffInsert, Path: "$.c", Value: []byte(`"<&>"`)}}, `{"a":2,"c":"<&>","bb":1}`},
+	}
+	for _, tc := range testcases {
+		res, err := ApplyJSONDiff([]byte(tc.doc), tc.diffs)
+		if err != nil {
+			t.Errorf("Unexpected error applying %+v to %s: %v", tc.diffs, tc.doc, err)
+			continue
+		}
+		if string(res) != tc.exp {
+			t.Errorf("Expected %s, got %s", tc.exp, res)
+		}
+	}
+
+	if _, err := ApplyJSONDiff([]byte(`{"a":1}`), []JSONDiff{{Op: JSONDiffReplace, Path: "$.a.b", Value: []byte(`1`)}}); err == nil {
+		t.Error("Expected an error for a path inside a scalar")
+	}
+}
@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// StopCondition describes where bounded reading ends. Reading stops at the
+// first transaction boundary where any of the conditions is met.
+type StopCondition struct {
+	// Position stops reading before the first transaction that starts at or
+	// after it.
+	Position binlog.Position
+	// GTIDs stops reading once every transaction of the set is executed.
+	GTIDs binlog.GTIDSet
+	// MariaDBGTID stops reading once the transaction is executed.
+	MariaDBGTID *binlog.MariaDBGTID
+	// Time stops reading before the first transaction that was logged at or
+	// after it.
+	Time time.Time
+}
+
+var (
+	// ErrEndOfRange is returned once the reader reaches its stop condition.
+	ErrEndOfRange = errors.New("End of range")
+)
+
+// StopAt limits the range of events that are read. Once the given condition
+// is met ErrEndOfRange is returned instead of the next event.
+func (r *Reader) StopAt(c StopCondition) {
+	r.stop = &c
+}
+
+// reachedStop returns true if the reader is at a transaction boundary and the
+// stop condition is met. Timestamp of the next event is checked if given.
+func (r *Reader) reachedStop(ts uint32) bool {
+	if r.stop == nil || r.gtid != nil || r.inTx || len(r.payload) > 0 {
+		return false
+	}
+
+	c := r.stop
+	if c.Position.File != "" && r.state.File != "" && r.state.Compare(c.Position) >= 0 {
+		return true
+	}
+	if !c.GTIDs.IsEmpty() && r.gtids.ContainsSet(c.GTIDs) {
+		return true
+	}
+	if c.MariaDBGTID != nil {
+		g, ok := r.mariaGTIDs.GTID(c.MariaDBGTID.DomainID)
+		if ok && g.SequenceNumber >= c.MariaDBGTID.SequenceNumber {
+			return true
+		}
+	}
+	// Artificial events have zero timestamps
+	if !c.Time.IsZero() && ts > 0 && !time.Unix(int64(ts), 0).Before(c.Time) {
+		return true
+	}
+	return false
+}
+
+// eventTimestamp returns the timestamp of an event without decoding it.
+func eventTimestamp(connBuff []byte) uint32 {
+	if len(connBuff) < 4 {
+		return 0
+	}
+	return mysql.DecodeUint32(connBuff)
+}
@@ -0,0 +1,98 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestStopAt(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	gtids, _ := binlog.ParseGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1")
+	testEvents := func(file string) [][]byte {
+		events := [][]byte{
+			testFormatDescriptionEvent(),
+			testRotateEvent(file),
+			testGTIDEvent(sid, 1),
+			testQueryEvent("testdb", "BEGIN"),
+			testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+			testXIDEvent(100),
+			testGTIDEvent(sid, 2),
+			testQueryEvent("testdb", "BEGIN"),
+			testQueryEvent("testdb", "INSERT INTO foo VALUES (2)"),
+			testXIDEvent(101),
+		}
+		for i, evt := range events {
+			if i == 1 {
+				// Rotate event is artificial
+				continue
+			}
+			ts := uint32(1000)
+			if i >= 6 {
+				ts = 2000
+			}
+			mysql.EncodeUint32(evt, ts)
+		}
+		return events
+	}
+	events := testEvents("mysql-bin.000001")
+	txStart := uint64(testFileSize(events[:6]...))
+	txMiddle := uint64(testFileSize(events[:4]...))
+
+	tests := []struct {
+		name string
+		file string
+		cond StopCondition
+		exp  int
+	}{
+		{"transaction start", "", StopCondition{Position: binlog.Position{File: "mysql-bin.000001", Offset: txStart}}, 6},
+		{"transaction middle", "", StopCondition{Position: binlog.Position{File: "mysql-bin.000001", Offset: txMiddle}}, 6},
+		{"previous file", "", StopCondition{Position: binlog.Position{File: "mysql-bin.000000", Offset: 4}}, 2},
+		// Sequence number of the file name grows past six digits
+		{"next file rollover", "mysql-bin.999999", StopCondition{Position: binlog.Position{File: "mysql-bin.1000000", Offset: 4}}, len(events)},
+		{"previous file rollover", "mysql-bin.1000000", StopCondition{Position: binlog.Position{File: "mysql-bin.999999", Offset: 4}}, 2},
+		{"gtid", "", StopCondition{GTIDs: gtids}, 6},
+		{"time", "", StopCondition{Time: time.Unix(2000, 0)}, 6},
+		{"future", "", StopCondition{Time: time.Unix(3000, 0)}, len(events)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := test.file
+			if file == "" {
+				file = "mysql-bin.000001"
+			}
+			src, err := NewBytesSource(testFileContents(testEvents(file)...))
+			if err != nil {
+				t.Fatalf("Failed to create source: %v", err)
+			}
+			r := NewFromSource(src)
+			r.StopAt(test.cond)
+
+			var n int
+			for ; ; n++ {
+				if _, err = r.ReadEvent(context.Background()); err != nil {
+					break
+				}
+			}
+			if n != test.exp {
+				t.Errorf("Expected %d events to be read, got %d", test.exp, n)
+			}
+			if test.exp == len(events) {
+				if err != io.EOF {
+					t.Errorf("Expected EOF, got %v", err)
+				}
+				return
+			}
+			if err != ErrEndOfRange {
+				t.Errorf("Expected end of range, got %v", err)
+			}
+			if _, err := r.ReadEvent(context.Background()); err != ErrEndOfRange {
+				t.Errorf("Expected end of range on subsequent read, got %v", err)
+			}
+		})
+	}
+}
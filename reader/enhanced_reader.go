@@ -17,8 +17,13 @@ import (
 // details to add column names and signed integers support.
 type EnhancedReader struct {
 	reader    *Reader
-	safepoint binlog.Position
 	schemaMgr *schema.Manager
+
+	// Tables matching the filter are managed once they are seen in the
+	// binary log or changed by a DDL statement. Names of seen tables are
+	// cached along with match results
+	filter tableFilter
+	seen   map[schema.TableName]bool
 }
 
 // EnhancedRowsEvent ...
@@ -40,24 +45,70 @@ func NewEnhanced(dsn string, sc driver.Config) (*EnhancedReader, error) {
 		return nil, err
 	}
 
-	return &EnhancedReader{
+	return NewEnhancedFromReader(r, conn), nil
+}
+
+// NewEnhancedFromReader creates a new enhanced binary log reader on top of the
+// given reader. Database connection is used to query table schemas, it doesn't
+// have to be the source of binary log events.
+func NewEnhancedFromReader(r *Reader, db *sql.DB) *EnhancedReader {
+	er := &EnhancedReader{
 		reader:    r,
-		schemaMgr: schema.NewManager(conn),
-		safepoint: r.state,
-	}, nil
+		schemaMgr: schema.NewManager(db),
+		seen:      make(map[schema.TableName]bool),
+	}
+	// Schema changes of matching tables are tracked before they are seen
+	er.schemaMgr.ManageMatching(er.filter.match)
+	return er
 }
 
 // WhitelistTables adds given tables of the given database to processing white
 // list.
 func (r *EnhancedReader) WhitelistTables(database string, tables ...string) error {
 	for _, tbl := range tables {
-		if err := r.schemaMgr.Manage(database, tbl); err != nil {
+		err := r.schemaMgr.ManageAt(r.reader.state, r.reader.lastGTID, database, tbl)
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// IncludeTables adds patterns of tables that are managed once they are seen in
+// the binary log or changed by a DDL statement, in addition to the whitelisted
+// ones. Patterns of the "database.table" form use glob syntax of path.Match
+// for each of the names, e.g. "shop_*.orders_*". Patterns enclosed in slashes
+// are regular expressions matched against the whole "database.table" name.
+func (r *EnhancedReader) IncludeTables(patterns ...string) error {
+	include, err := parseTablePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	r.filter.include = append(r.filter.include, include...)
+	r.seen = make(map[schema.TableName]bool)
+	return nil
+}
+
+// ExcludeTables adds patterns of tables that are not managed even if they
+// match include patterns, e.g. "*._gh_ost*". Whitelisted tables are not
+// affected. Pattern syntax is the same as for IncludeTables.
+func (r *EnhancedReader) ExcludeTables(patterns ...string) error {
+	exclude, err := parseTablePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	r.filter.exclude = append(r.filter.exclude, exclude...)
+	r.seen = make(map[schema.TableName]bool)
+	return nil
+}
+
+// UseSchemaHistory makes the reader record table definition changes into the
+// given history. Definitions of whitelisted tables are restored as they were
+// at the current position, so it should be called before WhitelistTables.
+func (r *EnhancedReader) UseSchemaHistory(h *schema.History) {
+	r.schemaMgr.UseHistory(h, r.reader.state, r.reader.gtids)
+}
+
 // ReadEvent reads next event from the binary log.
 func (r *EnhancedReader) ReadEvent(ctx context.Context) (*Event, error) {
 	evt, err := r.reader.ReadEvent(ctx)
@@ -66,20 +117,39 @@ func (r *EnhancedReader) ReadEvent(ctx context.Context) (*Event, error) {
 	}
 
 	switch evt.Header.Type {
-	case binlog.EventTypeQuery:
-		var qe binlog.QueryEvent
-		qe.Decode(evt.Buffer)
-		err = r.schemaMgr.ProcessQuery(string(qe.Schema), string(qe.Query))
+	case binlog.EventTypeTableMap:
+		if r.filter.isEmpty() {
+			break
+		}
+		var tme binlog.TableMapEvent
+		if err := tme.Decode(evt.Buffer, evt.Format); err != nil {
+			return nil, errors.Annotate(err, "decode table map event")
+		}
+		if err := r.manageMatching(tme.SchemaName, tme.TableName); err != nil {
+			return evt, err
+		}
+	case binlog.EventTypeQuery, binlog.EventTypeMariaDBQueryCompressed:
+		qe, err := evt.DecodeQuery()
+		if err != nil {
+			return nil, errors.Annotate(err, "decode query event")
+		}
+		// Position right after the statement is recorded in schema history
+		pos := r.reader.state
+		err = r.schemaMgr.ProcessQueryAt(pos, r.reader.lastGTID, string(qe.Schema), string(qe.Query))
+		if err != nil {
+			return evt, err
+		}
 	}
 
-	return evt, err
+	return evt, nil
 }
 
 // NextRowsEvent returns the next rows event for a whitelisted table. It blocks
 // until next event is received or context is cancelled.
 func (r *EnhancedReader) NextRowsEvent(ctx context.Context) (*EnhancedRowsEvent, error) {
 	for {
-		evt, err := r.reader.ReadEvent(ctx)
+		// Schema changes are processed along the way
+		evt, err := r.ReadEvent(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -109,15 +179,15 @@ func (r *EnhancedReader) NextRowsEvent(ctx context.Context) (*EnhancedRowsEvent,
 		for i, row := range re.Rows {
 			erow := make(map[string]interface{}, len(row))
 			for j, val := range row {
-				col := tbl.Column(j)
-				if col == nil {
+				name, unsigned, ok := columnDetails(evt.Table, tbl, j)
+				if !ok {
 					return nil, errors.New("column index undefined")
 				}
 				ct := mysql.ColumnType(evt.Table.ColumnTypes[j])
-				if !col.Unsigned {
+				if !unsigned {
 					val = signNumber(val, ct)
 				}
-				erow[col.Name] = val
+				erow[name] = val
 			}
 			ere.Rows[i] = erow
 		}
@@ -126,24 +196,32 @@ func (r *EnhancedReader) NextRowsEvent(ctx context.Context) (*EnhancedRowsEvent,
 	}
 }
 
-func (r *EnhancedReader) processEvent(evt Event) {
-	switch evt.Header.Type {
-	case binlog.EventTypeFormatDescription, binlog.EventTypeTableMap, binlog.EventTypeXID:
-		r.safepoint.Offset = evt.Offset
-	case binlog.EventTypeRotate:
-		r.safepoint = r.reader.state
-	}
-}
-
-// State returns current position in the binary log.
-func (r *EnhancedReader) State() binlog.Position {
-	return r.reader.state
+// State returns current position in the binary log and a set of executed
+// transactions.
+func (r *EnhancedReader) State() State {
+	return r.reader.State()
 }
 
 // Safepoint returns last encountered position that is considered safe to start
 // with.
 func (r *EnhancedReader) Safepoint() binlog.Position {
-	return r.safepoint
+	return r.reader.Safepoint()
+}
+
+// EnableReconnect makes the reader recover from connection failures according
+// to the given policy.
+func (r *EnhancedReader) EnableReconnect(p ReconnectPolicy) {
+	r.reader.EnableReconnect(p)
+}
+
+// StopAt limits the range of events that are read.
+func (r *EnhancedReader) StopAt(c StopCondition) {
+	r.reader.StopAt(c)
+}
+
+// Ack acknowledges that events up to the current position were received.
+func (r *EnhancedReader) Ack() error {
+	return r.reader.Ack()
 }
 
 // Close underlying database connection.
@@ -151,6 +229,50 @@ func (r *EnhancedReader) Close() error {
 	return r.reader.Close()
 }
 
+// manageMatching starts managing a table the first time it is seen if it
+// matches the filter.
+func (r *EnhancedReader) manageMatching(database, table string) error {
+	name := schema.TableName{Database: database, Table: table}
+	if _, ok := r.seen[name]; ok {
+		return nil
+	}
+	match := r.filter.match(database, table)
+	if match && !r.schemaMgr.IsManaged(database, table) {
+		err := r.schemaMgr.ManageAt(r.reader.state, r.reader.lastGTID, database, table)
+		if err != nil {
+			return errors.Annotate(err, "load table schema")
+		}
+	}
+	r.seen[name] = match
+	return nil
+}
+
+// columnDetails returns name and signedness of the column at given position.
+// Optional metadata of the table map event is used when it is present because
+// it always matches the rows event, while the schema could already be altered
+// by a DDL statement that follows it. Schema is only used for the details that
+// are not logged.
+func columnDetails(td *binlog.TableDescription, tbl *schema.Table, i int) (name string, unsigned, ok bool) {
+	meta := td.Metadata
+	if meta != nil && i < len(meta.ColumnNames) {
+		// Signedness is not logged for tables without numeric columns
+		if i < len(meta.Unsigned) {
+			unsigned = meta.Unsigned[i]
+		}
+		return meta.ColumnNames[i], unsigned, true
+	}
+
+	col := tbl.Column(i)
+	if col == nil {
+		return "", false, false
+	}
+	unsigned = col.Unsigned
+	if meta != nil && i < len(meta.Unsigned) {
+		unsigned = meta.Unsigned[i]
+	}
+	return col.Name, unsigned, true
+}
+
 func signNumber(val interface{}, ct mysql.ColumnType) interface{} {
 	switch tval := val.(type) {
 	case uint8:
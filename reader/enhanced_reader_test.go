@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/reader/schema"
+)
+
+func TestColumnDetails(t *testing.T) {
+	s := schema.NewSchema()
+	// Schema was altered after the rows event was logged
+	s.Update("testdb", "foo", []schema.Column{{Name: "id", Unsigned: true}, {Name: "renamed"}})
+	tbl := s.Table("testdb", "foo")
+
+	tests := []struct {
+		meta     *binlog.TableMetadata
+		name     string
+		unsigned bool
+	}{
+		{nil, "id", true},
+		{&binlog.TableMetadata{Unsigned: []bool{false, false}}, "id", false},
+		{&binlog.TableMetadata{Unsigned: []bool{false, false}, ColumnNames: []string{"num", "name"}}, "num", false},
+		{&binlog.TableMetadata{ColumnNames: []string{"title", "name"}}, "title", false},
+	}
+	for _, test := range tests {
+		td := &binlog.TableDescription{Metadata: test.meta}
+		name, unsigned, ok := columnDetails(td, tbl, 0)
+		if !ok || name != test.name || unsigned != test.unsigned {
+			t.Errorf("Expected column %q unsigned %v, got %q unsigned %v", test.name, test.unsigned, name, unsigned)
+		}
+	}
+
+	// Columns that are missing from the schema are only known from metadata
+	td := &binlog.TableDescription{Metadata: &binlog.TableMetadata{ColumnNames: []string{"a", "b", "c"}}}
+	if name, _, ok := columnDetails(td, tbl, 2); !ok || name != "c" {
+		t.Errorf("Expected column %q, got %q", "c", name)
+	}
+	if _, _, ok := columnDetails(&binlog.TableDescription{}, tbl, 2); ok {
+		t.Error("Expected column to be undefined")
+	}
+}
+
+func TestEnhancedReaderHistoryPosition(t *testing.T) {
+	db := testDB(map[string]testResult{
+		"\n\t\tSELECT\n\t\t\tCOLUMN_NAME": {
+			cols: []string{"COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_DEFAULT",
+				"CHARACTER_MAXIMUM_LENGTH", "CHARACTER_SET_NAME", "COLLATION_NAME", "EXTRA"},
+			rows: [][]string{{"id", "int(10) unsigned", "NO", "0", "10", "", "", ""}},
+		},
+		"\n\t\tSELECT INDEX_NAME": {
+			cols: []string{"INDEX_NAME", "NON_UNIQUE", "COLUMN_NAME"},
+		},
+	})
+	r := &Reader{state: binlog.Position{File: "mysql-bin.000001", Offset: 4}}
+	er := NewEnhancedFromReader(r, db)
+	h := schema.NewHistory()
+	er.UseSchemaHistory(h)
+
+	// Table definition is queried at the current position, not the one the
+	// history was attached at
+	pos := binlog.Position{File: "mysql-bin.000002", Offset: 100}
+	r.state = pos
+	if err := er.WhitelistTables("db", "foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(h.Entries) != 1 || h.Entries[0].Position != pos {
+		t.Errorf("Expected an entry at %v, got %+v", pos, h.Entries)
+	}
+}
@@ -0,0 +1,209 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// FileConfig describes a set of binary log files to read from.
+type FileConfig struct {
+	// File is a path to the binary log file to start with.
+	File string
+	// Offset is the binary offset of the first event in the binary log file,
+	// a starting point at which processing should begin.
+	Offset uint64
+	// IndexFile is a path to the binary log index file. When set, reading
+	// continues with the next file listed in the index once current file is
+	// over. Index entries are resolved relative to the index file location.
+	IndexFile string
+	// VerifyChecksum makes the reader verify event checksums if the files
+	// were written with checksums enabled.
+	VerifyChecksum bool
+}
+
+// fileSource reads binary log events from files on disk. It mimics the
+// behavior of a server that is dumping the binary log: a fake rotate event is
+// sent before the contents of each file and the format description event is
+// always sent first.
+type fileSource struct {
+	eventReader
+	conf FileConfig
+	path string
+	file *os.File
+
+	// Pending packets are delivered before reading from the file
+	pending [][]byte
+}
+
+// NewFromFile creates a new binary log reader that reads events from files on
+// disk. Once all files are read io.EOF is returned.
+func NewFromFile(conf FileConfig) (*Reader, error) {
+	src, err := NewFileSource(conf)
+	if err != nil {
+		return nil, err
+	}
+	r := NewFromSource(src)
+	r.conf.VerifyChecksum = conf.VerifyChecksum
+	return r, nil
+}
+
+// NewFileSource creates a source that reads events from files on disk.
+func NewFileSource(conf FileConfig) (Source, error) {
+	if conf.Offset < uint64(len(binlogMagic)) {
+		conf.Offset = uint64(len(binlogMagic))
+	}
+
+	src := &fileSource{conf: conf}
+	if err := src.open(conf.File, conf.Offset); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// ReadPacket reads next event from the file.
+func (s *fileSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.pending) > 0 {
+		p := s.pending[0]
+		s.pending = s.pending[1:]
+		return p, nil
+	}
+
+	data, err := s.readEvent()
+	if err != io.EOF {
+		return data, err
+	}
+
+	next, err := s.nextFile()
+	if err != nil {
+		return nil, err
+	}
+	if next == "" {
+		return nil, io.EOF
+	}
+	if err := s.open(next, uint64(len(binlogMagic))); err != nil {
+		return nil, err
+	}
+	return s.ReadPacket(ctx)
+}
+
+// Close closes current file.
+func (s *fileSource) Close() error {
+	return s.file.Close()
+}
+
+// open opens given file, checks that it is a binary log file and prepares
+// packets for delivery: a fake rotate event and a format description event if
+// reading starts past it.
+func (s *fileSource) open(path string, offset uint64) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Annotate(err, "open binary log file")
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.path = path
+	s.file = f
+	s.rd = bufio.NewReader(f)
+	if err := s.readMagic(); err != nil {
+		return err
+	}
+
+	s.pending = append(s.pending, s.fakeRotateEvent(filepath.Base(path), offset))
+	if offset <= uint64(len(binlogMagic)) {
+		return nil
+	}
+
+	// Format description event is required to decode any other event
+	fde, err := s.readEvent()
+	if err != nil {
+		return errors.Annotate(err, "read format description event")
+	}
+	if binlog.EventType(fde[4]) == binlog.EventTypeFormatDescription {
+		// Next position is set to zero to let the reader know this event is
+		// not part of the requested range, just like the server does
+		mysql.EncodeUint32(fde[13:], 0)
+		if s.checksum {
+			n := len(fde) - 4
+			mysql.EncodeUint32(fde[n:], crc32.ChecksumIEEE(fde[:n]))
+		}
+		s.pending = append(s.pending, fde)
+	}
+
+	if _, err := f.Seek(int64(offset), io.SeekStart); err != nil {
+		return errors.Annotate(err, "seek binary log file")
+	}
+	s.rd.Reset(f)
+	return nil
+}
+
+// fakeRotateEvent creates a rotate event that is not part of the file. Server
+// sends the same kind of event at the beginning of a binlog dump.
+func (s *fileSource) fakeRotateEvent(name string, offset uint64) []byte {
+	const (
+		headerLen      = 19
+		flagArtificial = 0x20
+	)
+
+	size := headerLen + 8 + len(name)
+	if s.checksum {
+		size += 4
+	}
+	data := make([]byte, size)
+	data[4] = byte(binlog.EventTypeRotate)
+	mysql.EncodeUint32(data[9:], uint32(size))
+	mysql.EncodeUint16(data[17:], flagArtificial)
+	mysql.EncodeUint64(data[headerLen:], offset)
+	copy(data[headerLen+8:], name)
+	if s.checksum {
+		n := len(data) - 4
+		mysql.EncodeUint32(data[n:], crc32.ChecksumIEEE(data[:n]))
+	}
+	return data
+}
+
+// nextFile returns a path to the file that follows current one in the index.
+// Empty string is returned if there's no index or current file is the last
+// one.
+func (s *fileSource) nextFile() (string, error) {
+	if s.conf.IndexFile == "" {
+		return "", nil
+	}
+
+	index, err := ioutil.ReadFile(s.conf.IndexFile)
+	if err != nil {
+		return "", errors.Annotate(err, "read index file")
+	}
+
+	cur := filepath.Base(s.path)
+	found := false
+	for _, line := range strings.Split(string(index), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if found {
+			return filepath.Join(filepath.Dir(s.conf.IndexFile), filepath.Base(line)), nil
+		}
+		found = filepath.Base(line) == cur
+	}
+	return "", nil
+}
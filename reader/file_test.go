@@ -0,0 +1,225 @@
+package reader
+
+import (
+	"context"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestFileReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bocadillo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		file1 = "mysql-bin.000001"
+		file2 = "mysql-bin.000002"
+	)
+	events1 := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+	}
+	events1 = append(events1, testRotateEvent(file2))
+	events2 := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "DROP TABLE foo"),
+	}
+	writeTestFile(t, filepath.Join(dir, file1), events1...)
+	writeTestFile(t, filepath.Join(dir, file2), events2...)
+	index := filepath.Join(dir, "mysql-bin.index")
+	if err := ioutil.WriteFile(index, []byte("./"+file1+"\n./"+file2+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewFromFile(FileConfig{File: filepath.Join(dir, file1), IndexFile: index})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	var queries []string
+	for {
+		evt, err := r.ReadEvent(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if evt.Header.Type == binlog.EventTypeQuery {
+			var qe binlog.QueryEvent
+			if err := qe.Decode(evt.Buffer); err != nil {
+				t.Fatalf("Failed to decode query event: %v", err)
+			}
+			queries = append(queries, string(qe.Query))
+		}
+	}
+
+	if len(queries) != 2 || queries[0] != "CREATE TABLE foo (id INT)" || queries[1] != "DROP TABLE foo" {
+		t.Errorf("Unexpected queries: %q", queries)
+	}
+	exp := binlog.Position{File: file2, Offset: uint64(testFileSize(events2...))}
+	if st := r.State(); st.Position != exp {
+		t.Errorf("Expected position %v, got %v", exp, st.Position)
+	}
+}
+
+func TestFileReaderOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bocadillo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+		testQueryEvent("testdb", "DROP TABLE foo"),
+	}
+	path := filepath.Join(dir, "mysql-bin.000001")
+	writeTestFile(t, path, events...)
+
+	offset := uint64(testFileSize(events[:2]...))
+	r, err := NewFromFile(FileConfig{File: path, Offset: offset})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	var types []binlog.EventType
+	var firstOffset uint64
+	for {
+		evt, err := r.ReadEvent(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if evt.Header.Type == binlog.EventTypeQuery && firstOffset == 0 {
+			firstOffset = evt.Offset
+		}
+		types = append(types, evt.Header.Type)
+	}
+
+	expTypes := []binlog.EventType{binlog.EventTypeRotate, binlog.EventTypeFormatDescription, binlog.EventTypeQuery}
+	if len(types) != len(expTypes) {
+		t.Fatalf("Expected events %v, got %v", expTypes, types)
+	}
+	for i := range types {
+		if types[i] != expTypes[i] {
+			t.Errorf("Expected event #%d to be %s, got %s", i, expTypes[i], types[i])
+		}
+	}
+	if firstOffset != offset {
+		t.Errorf("Expected first event offset %d, got %d", offset, firstOffset)
+	}
+}
+
+func TestFileReaderInvalidMagic(t *testing.T) {
+	f, err := ioutil.TempFile("", "bocadillo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not a binlog")
+	f.Close()
+
+	if _, err := NewFromFile(FileConfig{File: f.Name()}); err != ErrInvalidMagic {
+		t.Errorf("Expected invalid magic error, got %v", err)
+	}
+}
+
+//
+// Helpers
+//
+
+func writeTestFile(t *testing.T, path string, events ...[]byte) {
+	if err := ioutil.WriteFile(path, testFileContents(events...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testFileContents sets next positions and checksums of given events and
+// returns them as binary log file contents.
+func testFileContents(events ...[]byte) []byte {
+	setTestOffsets(events...)
+	data := append([]byte{}, binlogMagic...)
+	for _, evt := range events {
+		data = append(data, evt...)
+	}
+	return data
+}
+
+// setTestOffsets sets next positions and checksums of given events as if they
+// were written to a binary log file one after another.
+func setTestOffsets(events ...[]byte) {
+	offset := len(binlogMagic)
+	for _, evt := range events {
+		offset += len(evt)
+		mysql.EncodeUint32(evt[13:], uint32(offset))
+		setTestChecksum(evt)
+	}
+}
+
+func testFileSize(events ...[]byte) int {
+	size := len(binlogMagic)
+	for _, evt := range events {
+		size += len(evt)
+	}
+	return size
+}
+
+// testEvent builds an event with given type and body, next position is set
+// later and space for a CRC32 checksum is reserved at the end.
+func testEvent(et binlog.EventType, body []byte) []byte {
+	data := make([]byte, 19+len(body)+4)
+	data[4] = byte(et)
+	mysql.EncodeUint32(data[5:], 1)
+	mysql.EncodeUint32(data[9:], uint32(len(data)))
+	copy(data[19:], body)
+	return data
+}
+
+func setTestChecksum(evt []byte) {
+	n := len(evt) - 4
+	mysql.EncodeUint32(evt[n:], crc32.ChecksumIEEE(evt[:n]))
+}
+
+func testFormatDescriptionEvent() []byte {
+	body := make([]byte, 2+50+4+1+int(binlog.EventTypePreviousGTIDs)+1)
+	mysql.EncodeUint16(body, 4)
+	copy(body[2:], "5.7.30-log")
+	body[56] = 19
+	for i := 57; i < len(body)-1; i++ {
+		body[i] = 6
+	}
+	body[56+int(binlog.EventTypeQuery)] = 13
+	body[56+int(binlog.EventTypeRotate)] = 8
+	body[len(body)-1] = byte(binlog.ChecksumAlgorithmCRC32)
+	return testEvent(binlog.EventTypeFormatDescription, body)
+}
+
+func testQueryEvent(schema, query string) []byte {
+	body := make([]byte, 13+len(schema)+1+len(query))
+	body[8] = byte(len(schema))
+	copy(body[13:], schema)
+	copy(body[13+len(schema)+1:], query)
+	return testEvent(binlog.EventTypeQuery, body)
+}
+
+func testRotateEvent(file string) []byte {
+	body := make([]byte, 8+len(file))
+	mysql.EncodeUint64(body, 4)
+	copy(body[8:], file)
+	return testEvent(binlog.EventTypeRotate, body)
+}
@@ -0,0 +1,98 @@
+package reader
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// tableFilter selects tables by their database and table names. A table is
+// selected if it matches any of the include patterns and none of the exclude
+// patterns.
+type tableFilter struct {
+	include []tablePattern
+	exclude []tablePattern
+}
+
+// tablePattern matches qualified table names. It is either a pair of glob
+// patterns for database and table names or a regular expression that is
+// matched against the whole "database.table" name.
+type tablePattern struct {
+	database string
+	table    string
+	re       *regexp.Regexp
+}
+
+var (
+	// ErrInvalidTablePattern is returned when a table pattern can't be
+	// parsed.
+	ErrInvalidTablePattern = errors.New("Invalid table pattern")
+)
+
+// parseTablePattern parses a table pattern. Patterns of the "database.table"
+// form use glob syntax of path.Match for each of the names, e.g.
+// "shop_*.orders_*". Patterns enclosed in slashes are regular expressions,
+// e.g. "/^shop_[0-9]+\.orders$/".
+func parseTablePattern(str string) (tablePattern, error) {
+	if len(str) > 1 && str[0] == '/' && str[len(str)-1] == '/' {
+		re, err := regexp.Compile(str[1 : len(str)-1])
+		if err != nil {
+			return tablePattern{}, errors.Annotate(err, "compile table pattern")
+		}
+		return tablePattern{re: re}, nil
+	}
+
+	i := strings.IndexByte(str, '.')
+	if i < 0 {
+		return tablePattern{}, errors.Annotate(ErrInvalidTablePattern, str)
+	}
+	p := tablePattern{database: str[:i], table: str[i+1:]}
+	for _, glob := range []string{p.database, p.table} {
+		if _, err := path.Match(glob, ""); err != nil {
+			return tablePattern{}, errors.Annotate(ErrInvalidTablePattern, str)
+		}
+	}
+	return p, nil
+}
+
+func (p tablePattern) match(database, table string) bool {
+	if p.re != nil {
+		return p.re.MatchString(database + "." + table)
+	}
+	// Patterns are validated upon parsing
+	dbOK, _ := path.Match(p.database, database)
+	tblOK, _ := path.Match(p.table, table)
+	return dbOK && tblOK
+}
+
+// parseTablePatterns parses a list of table patterns.
+func parseTablePatterns(strs []string) ([]tablePattern, error) {
+	patterns := make([]tablePattern, 0, len(strs))
+	for _, str := range strs {
+		p, err := parseTablePattern(str)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func (f *tableFilter) match(database, table string) bool {
+	return matchAny(f.include, database, table) && !matchAny(f.exclude, database, table)
+}
+
+func (f *tableFilter) isEmpty() bool {
+	return len(f.include) == 0
+}
+
+func matchAny(patterns []tablePattern, database, table string) bool {
+	for _, p := range patterns {
+		if p.match(database, table) {
+			return true
+		}
+	}
+	return false
+}
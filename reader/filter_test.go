@@ -0,0 +1,144 @@
+package reader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+	"github.com/localhots/bocadillo/reader/schema"
+)
+
+func TestTableFilter(t *testing.T) {
+	var f tableFilter
+	var err error
+	f.include, err = parseTablePatterns([]string{"shop_*.orders_*", `/^billing\.(invoices|payments)$/`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.exclude, err = parseTablePatterns([]string{"*._gh_ost*", "shop_test.*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		database string
+		table    string
+		exp      bool
+	}{
+		{"shop_1", "orders_2024", true},
+		{"shop_1", "orders", false},
+		{"shop_1", "_gh_ost_orders_2024", false},
+		{"shop_test", "orders_2024", false},
+		{"billing", "invoices", true},
+		{"billing", "invoices_old", false},
+		{"other", "orders_2024", false},
+	}
+	for _, test := range tests {
+		if res := f.match(test.database, test.table); res != test.exp {
+			t.Errorf("Expected match to be %v for %s.%s", test.exp, test.database, test.table)
+		}
+	}
+
+	for _, str := range []string{"orders", "shop.[", "/(/"} {
+		if _, err := parseTablePattern(str); err == nil {
+			t.Errorf("Expected pattern %q to be invalid", str)
+		}
+	}
+}
+
+func TestEnhancedReaderAutoManage(t *testing.T) {
+	src, err := NewBytesSource(testFileContents(
+		testFormatDescriptionEvent(),
+		testTableMapEvent(1, "shop_1", "orders_2024"),
+		testTableMapEvent(2, "shop_1", "_gh_ost_orders_2024"),
+		testTableMapEvent(3, "other", "orders_2024"),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	r := NewEnhancedFromReader(NewFromSource(src), nil)
+
+	// Definitions are restored from history, database is not available
+	h := schema.NewHistory()
+	for _, name := range []string{"orders_2024", "_gh_ost_orders_2024"} {
+		h.Record(schema.HistoryEntry{
+			Table:   schema.TableName{Database: "shop_1", Table: name},
+			Columns: []schema.Column{{Name: "id"}},
+		})
+	}
+	r.schemaMgr.UseHistory(h, binlog.Position{File: "mysql-bin.000001", Offset: 4}, binlog.GTIDSet{})
+
+	if err := r.IncludeTables("shop_*.orders_*"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := r.ExcludeTables("*._gh_ost*"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := r.ReadEvent(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if !r.schemaMgr.IsManaged("shop_1", "orders_2024") || r.schemaMgr.Schema.Table("shop_1", "orders_2024") == nil {
+		t.Errorf("Expected matching table to be managed")
+	}
+	if r.schemaMgr.IsManaged("shop_1", "_gh_ost_orders_2024") {
+		t.Errorf("Expected excluded table not to be managed")
+	}
+	if r.schemaMgr.IsManaged("other", "orders_2024") {
+		t.Errorf("Expected table of another database not to be managed")
+	}
+}
+
+func TestEnhancedReaderTracksMatchingDDL(t *testing.T) {
+	src, err := NewBytesSource(testFileContents(
+		testFormatDescriptionEvent(),
+		testQueryEvent("shop_1", "CREATE TABLE orders_2025 (id INT)"),
+		testQueryEvent("shop_1", "ALTER TABLE orders_2025 ADD COLUMN total DECIMAL(10,2)"),
+		testQueryEvent("shop_1", "CREATE TABLE _gh_ost_orders_2025 (id INT)"),
+		testTableMapEvent(1, "shop_1", "orders_2025"),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	// Database is not available, definitions are evolved offline
+	r := NewEnhancedFromReader(NewFromSource(src), nil)
+	if err := r.IncludeTables("shop_*.orders_*"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := r.ExcludeTables("*._gh_ost*"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := r.ReadEvent(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tbl := r.schemaMgr.Schema.Table("shop_1", "orders_2025")
+	if tbl == nil {
+		t.Fatalf("Expected matching table to be managed")
+	}
+	if col := tbl.Column(1); col == nil || col.Name != "total" || col.Type != "decimal" {
+		t.Errorf("Expected added column to be tracked, got %+v", col)
+	}
+	if r.schemaMgr.IsManaged("shop_1", "_gh_ost_orders_2025") {
+		t.Errorf("Expected excluded table not to be managed")
+	}
+}
+
+// testTableMapEvent builds a table map event of a table with a single INT
+// column.
+func testTableMapEvent(id uint32, database, table string) []byte {
+	body := make([]byte, 0, 4+2+len(database)+len(table)+8)
+	body = append(body, 0, 0, 0, 0, 0, 0)
+	mysql.EncodeUint32(body, id)
+	body = append(body, byte(len(database)))
+	body = append(body, database...)
+	body = append(body, 0, byte(len(table)))
+	body = append(body, table...)
+	body = append(body, 0, 1, byte(mysql.ColumnTypeLong), 0, 0)
+	return testEvent(binlog.EventTypeTableMap, body)
+}
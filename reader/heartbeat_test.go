@@ -0,0 +1,76 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// testPacketSource returns given packets one by one.
+type testPacketSource struct {
+	packets  [][]byte
+	checksum bool
+}
+
+func (s *testPacketSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	if len(s.packets) == 0 {
+		// Master stays silent
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	data := s.packets[0]
+	s.packets = s.packets[1:]
+	if data == nil {
+		return nil, io.EOF
+	}
+	return data, nil
+}
+
+func (s *testPacketSource) Checksum() bool {
+	return s.checksum
+}
+
+func (s *testPacketSource) Close() error {
+	return nil
+}
+
+func TestHeartbeat(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+	}
+	setTestOffsets(events...)
+	hb := testEvent(binlog.EventTypeHeartbeet, []byte("mysql-bin.000002"))
+	mysql.EncodeUint32(hb[13:], 1000)
+	setTestChecksum(hb)
+
+	r := NewFromSource(&testPacketSource{packets: append(events, hb, nil)})
+	if types := readTestEventTypes(t, r); len(types) != len(events) {
+		t.Errorf("Expected heartbeat not to be delivered, got %v", types)
+	}
+	exp := binlog.Position{File: "mysql-bin.000002", Offset: 1000}
+	if r.State().Position != exp {
+		t.Errorf("Expected position %v, got %v", exp, r.State().Position)
+	}
+	if r.Safepoint() != exp {
+		t.Errorf("Expected safepoint %v, got %v", exp, r.Safepoint())
+	}
+
+	r.conf.HeartbeatPeriod = 10 * time.Millisecond
+	_, err := r.ReadEvent(context.Background())
+	if errors.Cause(err) != ErrHeartbeatTimeout {
+		t.Errorf("Expected heartbeat timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = r.ReadEvent(ctx)
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+}
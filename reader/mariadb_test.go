@@ -0,0 +1,114 @@
+package reader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestMariaDBGTIDTracking(t *testing.T) {
+	events := [][]byte{
+		testMariaDBFormatDescriptionEvent(),
+		testMariaDBGTIDListEvent(binlog.MariaDBGTID{DomainID: 0, ServerID: 1, SequenceNumber: 9}),
+		testMariaDBGTIDEvent(0, 10, binlog.MariaDBGTIDFlagStandalone),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+		testMariaDBGTIDEvent(0, 11, 0),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(100),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	r := NewFromSource(src)
+	ctx := context.Background()
+
+	tx, err := r.NextTransaction(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.MariaDBGTID == nil || tx.MariaDBGTID.String() != "0-1-10" || len(tx.Queries) != 1 {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+	if exp := "0-1-10"; r.State().MariaDBGTIDs.String() != exp {
+		t.Errorf("Expected MariaDB GTIDs %q, got %q", exp, r.State().MariaDBGTIDs.String())
+	}
+
+	// Transactional GTID event is not terminated by a query
+	evt, err := r.ReadEvent(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if evt.Header.Type != binlog.EventTypeMariaDBGTID {
+		t.Fatalf("Expected MariaDB GTID event, got %s", evt.Header.Type)
+	}
+	if _, err := r.ReadEvent(ctx); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if r.Safepoint() == r.State().Position {
+		t.Errorf("Expected safepoint not to move inside a transaction")
+	}
+
+	if _, err := r.ReadEvent(ctx); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if exp := "0-1-11"; r.State().MariaDBGTIDs.String() != exp {
+		t.Errorf("Expected MariaDB GTIDs %q, got %q", exp, r.State().MariaDBGTIDs.String())
+	}
+	if r.Safepoint() != r.State().Position {
+		t.Errorf("Expected safepoint %v, got %v", r.State().Position, r.Safepoint())
+	}
+}
+
+func TestMariaDBFakeRotateEvent(t *testing.T) {
+	events := [][]byte{
+		testMariaDBFormatDescriptionEvent(),
+		testMariaDBGTIDListEvent(binlog.MariaDBGTID{DomainID: 0, ServerID: 1, SequenceNumber: 9}),
+		testMariaDBGTIDEvent(0, 10, binlog.MariaDBGTIDFlagStandalone),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+	}
+	setTestOffsets(events...)
+	// Dump with GTID positioning starts with a fake rotate event
+	rotate := testRotateEvent("mariadb-bin.000002")
+	setTestChecksum(rotate)
+	packets := append([][]byte{rotate}, events...)
+
+	r := NewFromSource(&testPacketSource{packets: append(packets, nil), checksum: true})
+	readTestEventTypes(t, r)
+	exp := binlog.Position{File: "mariadb-bin.000002", Offset: uint64(testFileSize(events...))}
+	if r.State().Position != exp {
+		t.Errorf("Expected position %v, got %v", exp, r.State().Position)
+	}
+	if exp := "0-1-10"; r.State().MariaDBGTIDs.String() != exp {
+		t.Errorf("Expected MariaDB GTIDs %q, got %q", exp, r.State().MariaDBGTIDs.String())
+	}
+}
+
+func testMariaDBFormatDescriptionEvent() []byte {
+	evt := testFormatDescriptionEvent()
+	version := evt[19+2 : 19+2+50]
+	copy(version, make([]byte, 50))
+	copy(version, "10.5.8-MariaDB-log")
+	return evt
+}
+
+func testMariaDBGTIDEvent(domain uint32, seq uint64, flags byte) []byte {
+	body := make([]byte, 8+4+1+6)
+	mysql.EncodeUint64(body, seq)
+	mysql.EncodeUint32(body[8:], domain)
+	body[12] = flags
+	return testEvent(binlog.EventTypeMariaDBGTID, body)
+}
+
+func testMariaDBGTIDListEvent(gtids ...binlog.MariaDBGTID) []byte {
+	body := make([]byte, 4+16*len(gtids))
+	mysql.EncodeUint32(body, uint32(len(gtids)))
+	for i, g := range gtids {
+		mysql.EncodeUint32(body[4+16*i:], g.DomainID)
+		mysql.EncodeUint32(body[8+16*i:], g.ServerID)
+		mysql.EncodeUint64(body[12+16*i:], g.SequenceNumber)
+	}
+	return testEvent(binlog.EventTypeMariaDBGTIDList, body)
+}
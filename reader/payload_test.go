@@ -0,0 +1,83 @@
+package reader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestTransactionPayload(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testGTIDEvent(sid, 1),
+		testPayloadEvent(t,
+			testQueryEvent("testdb", "BEGIN"),
+			testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+			testXIDEvent(100),
+		),
+		testGTIDEvent(sid, 2),
+		testQueryEvent("testdb", "CREATE TABLE bar (id INT)"),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	r := NewFromSource(src)
+	ctx := context.Background()
+
+	tx, err := r.NextTransaction(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.GTID == nil || tx.GTID.GNO != 1 || tx.XID != 100 {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+	if len(tx.Queries) != 1 || tx.Queries[0] != "INSERT INTO foo VALUES (1)" {
+		t.Errorf("Unexpected queries: %q", tx.Queries)
+	}
+	if exp := uint64(testFileSize(events[:3]...)); tx.End.Offset != exp {
+		t.Errorf("Expected transaction to end at %d, got %d", exp, tx.End.Offset)
+	}
+
+	evt, err := r.ReadEvent(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if exp := uint64(testFileSize(events[:3]...)); evt.Header.Type != binlog.EventTypeGTID || evt.Offset != exp {
+		t.Errorf("Expected GTID event at %d, got %s at %d", exp, evt.Header.Type, evt.Offset)
+	}
+}
+
+// testPayloadEvent compresses given events into a transaction payload event.
+// Embedded events are not checksummed.
+func testPayloadEvent(t *testing.T, events ...[]byte) []byte {
+	var payload []byte
+	for _, evt := range events {
+		evt = evt[:len(evt)-4]
+		mysql.EncodeUint32(evt[9:], uint32(len(evt)))
+		payload = append(payload, evt...)
+	}
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := enc.EncodeAll(payload, nil)
+
+	field := func(typ byte, v uint64) []byte {
+		val := make([]byte, 9)
+		n := mysql.EncodeUintLenEnc(val, v, false)
+		return append([]byte{typ, byte(n)}, val[:n]...)
+	}
+	var body []byte
+	body = append(body, field(1, uint64(len(compressed)))...)
+	body = append(body, field(2, uint64(binlog.CompressionTypeZSTD))...)
+	body = append(body, field(3, uint64(len(payload)))...)
+	body = append(body, 0)
+	body = append(body, compressed...)
+	return testEvent(binlog.EventTypeTransactionPayload, body)
+}
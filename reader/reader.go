@@ -1,19 +1,68 @@
 package reader
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
 	"github.com/localhots/bocadillo/mysql/driver"
 )
 
 // Reader is a binary log reader.
 type Reader struct {
-	conn     *driver.Conn
-	state    binlog.Position
-	format   binlog.FormatDescription
-	tableMap map[uint64]binlog.TableDescription
+	dsn       string
+	conf      driver.Config
+	src       Source
+	state     binlog.Position
+	safepoint binlog.Position
+	format    binlog.FormatDescription
+	tableMap  map[uint64]binlog.TableDescription
+
+	// Executed GTID set is updated once a transaction is complete
+	gtids binlog.GTIDSet
+	gtid  *binlog.GTIDEvent
+	inTx  bool
+	// GTID of the last committed transaction in textual form
+	lastGTID string
+	// Transaction collected by NextTransaction so far
+	tx *Transaction
+
+	// MariaDB replication position is updated once a transaction is complete
+	mariaGTIDs binlog.MariaDBGTIDSet
+	mariaGTID  *binlog.MariaDBGTID
+
+	// Events embedded into a transaction payload event are delivered before
+	// reading from the source. Position is advanced past the payload event
+	// once the last of them is delivered.
+	payload    [][]byte
+	payloadEnd uint64
+	payloadAck bool
+
+	// Reconnect policy is nil unless enabled explicitly. Events up to the
+	// resume position are not delivered after reconnecting because they were
+	// delivered before the connection was lost.
+	reconnect *ReconnectPolicy
+	resume    *binlog.Position
+
+	// Stop condition is nil unless reading is bounded
+	stop    *StopCondition
+	stopped bool
+}
+
+// State describes current position in the binary log alongside the set of
+// transactions executed up to this position.
+type State struct {
+	binlog.Position
+	ExecutedGTIDs binlog.GTIDSet
+	// MariaDBGTIDs is the last transaction executed in each replication
+	// domain, it is only maintained for MariaDB servers.
+	MariaDBGTIDs binlog.MariaDBGTIDSet
 }
 
 // Event contains binlog event details.
@@ -25,62 +74,280 @@ type Event struct {
 
 	// Table is not empty for rows events
 	Table *binlog.TableDescription
+
+	// AckRequested is true if semi-synchronous master waits for the event to
+	// be acknowledged using Reader.Ack.
+	AckRequested bool
 }
 
 var (
 	// ErrUnknownTableID is returned when a table ID from a rows event is
 	// missing in the table map index.
 	ErrUnknownTableID = errors.New("Unknown table ID")
+	// ErrHeartbeatTimeout is returned when master stays silent for longer
+	// than two heartbeat periods, connection is considered lost.
+	ErrHeartbeatTimeout = errors.New("Heartbeat timeout")
 )
 
+// heartbeatTimeoutFactor is the number of heartbeat periods after which a
+// silent connection is considered lost.
+const heartbeatTimeoutFactor = 2
+
+// ChecksumError is returned when a checksum of the event does not match its
+// contents.
+type ChecksumError struct {
+	Position binlog.Position
+	Expected uint32
+	Actual   uint32
+}
+
 // New creates a new binary log reader.
 func New(dsn string, sc driver.Config) (*Reader, error) {
-	conn, err := driver.Connect(dsn, sc)
+	gtids, err := binlog.ParseGTIDSet(sc.GTIDSet)
 	if err != nil {
-		return nil, errors.Annotate(err, "establish connection")
+		return nil, errors.Annotate(err, "parse gtid set")
+	}
+	mariaGTIDs, err := binlog.ParseMariaDBGTIDSet(sc.MariaDBGTIDSet)
+	if err != nil {
+		return nil, errors.Annotate(err, "parse mariadb gtid set")
 	}
 
 	r := &Reader{
-		conn: conn,
+		dsn:        dsn,
+		conf:       sc,
+		gtids:      gtids,
+		mariaGTIDs: mariaGTIDs,
 		state: binlog.Position{
 			File:   sc.File,
 			Offset: uint64(sc.Offset),
 		},
 	}
+	if sc.GTIDSet != "" || sc.MariaDBGTIDSet != "" {
+		// File name would be sent by the server in a fake rotate event
+		r.state = binlog.Position{}
+	}
+	r.safepoint = r.state
 	r.initTableMap()
 
-	if err := conn.DisableChecksum(); err != nil {
-		return nil, errors.Annotate(err, "disable binlog checksum")
+	if !sc.SkipValidation {
+		if err := validateServer(context.Background(), dsn, sc); err != nil {
+			return nil, errors.Annotate(err, "validate server")
+		}
 	}
-	if err := conn.RegisterSlave(); err != nil {
-		return nil, errors.Annotate(err, "register replica server")
+	if r.src, err = NewConnSource(dsn, sc); err != nil {
+		return nil, err
 	}
-	if err := conn.StartBinlogDump(); err != nil {
-		return nil, errors.Annotate(err, "start binlog dump")
+	return r, nil
+}
+
+// NewFromSource creates a new binary log reader that reads events from the
+// given source. Reconnect is not supported for such readers.
+func NewFromSource(src Source) *Reader {
+	r := &Reader{src: src}
+	r.initTableMap()
+	return r
+}
+
+// EnableReconnect makes the reader recover from connection failures according
+// to the given policy. Reading is resumed from the last transaction boundary,
+// events that were already delivered are skipped.
+func (r *Reader) EnableReconnect(p ReconnectPolicy) {
+	r.reconnect = &p
+}
+
+// NewConnSource establishes a new replica connection and starts a binary log
+// dump. Connection could be used as an event source.
+func NewConnSource(dsn string, sc driver.Config) (Source, error) {
+	conn, err := driver.Connect(dsn, sc)
+	if err != nil {
+		return nil, errors.Annotate(err, "establish connection")
 	}
 
-	return r, nil
+	if err := startDump(conn, sc); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
 }
 
-// ReadEvent reads next event from the binary log.
-func (r *Reader) ReadEvent(ctx context.Context) (*Event, error) {
-	connBuff, err := r.conn.ReadPacket(ctx)
+func startDump(conn *driver.Conn, sc driver.Config) error {
+	if sc.SemiSync {
+		if err := conn.EnableSemiSync(); err != nil {
+			return errors.Annotate(err, "enable semi-sync replication")
+		}
+	}
+	if sc.HeartbeatPeriod > 0 {
+		if err := conn.SetHeartbeatPeriod(sc.HeartbeatPeriod); err != nil {
+			return errors.Annotate(err, "set heartbeat period")
+		}
+	}
+	if sc.VerifyChecksum {
+		if err := conn.EnableChecksum(); err != nil {
+			return errors.Annotate(err, "enable binlog checksum")
+		}
+	} else {
+		if err := conn.DisableChecksum(); err != nil {
+			return errors.Annotate(err, "disable binlog checksum")
+		}
+	}
+	flavor, err := conn.Flavor()
 	if err != nil {
-		return nil, errors.Annotate(err, "read next event")
+		return errors.Annotate(err, "detect server flavor")
+	}
+	if flavor == binlog.FlavorMariaDB {
+		if err := conn.EnableMariaDBCapability(); err != nil {
+			return errors.Annotate(err, "enable mariadb capability")
+		}
+	}
+	if err := conn.RegisterSlave(); err != nil {
+		return errors.Annotate(err, "register replica server")
+	}
+	if sc.MariaDBGTIDSet != "" {
+		if err := conn.StartBinlogDumpMariaDBGTID(); err != nil {
+			return errors.Annotate(err, "start binlog dump with mariadb gtid")
+		}
+	} else if sc.GTIDSet != "" {
+		if err := conn.StartBinlogDumpGTID(); err != nil {
+			return errors.Annotate(err, "start binlog dump with gtid")
+		}
+	} else {
+		if err := conn.StartBinlogDump(); err != nil {
+			return errors.Annotate(err, "start binlog dump")
+		}
+	}
+	return nil
+}
+
+// ReadEvent reads next event from the binary log. io.EOF is returned once
+// there are no more events to read, e.g. when a non-blocking dump reaches the
+// end of the binary log.
+func (r *Reader) ReadEvent(ctx context.Context) (*Event, error) {
+	for {
+		if r.stopped {
+			return nil, ErrEndOfRange
+		}
+		if len(r.payload) > 0 {
+			connBuff := r.payload[0]
+			r.payload = r.payload[1:]
+			evt, err := r.decodeEvent(connBuff, true)
+			if err != nil {
+				return nil, err
+			}
+			if len(r.payload) == 0 {
+				// Acknowledgement of the payload event is requested after
+				// the last embedded event
+				evt.AckRequested = r.payloadAck
+			}
+			if r.isDelivered() {
+				continue
+			}
+			return evt, nil
+		}
+
+		connBuff, err := r.readPacket(ctx)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			if !r.canReconnect() || !isConnectionError(err) {
+				return nil, errors.Annotate(err, "read next event")
+			}
+			if err := r.resumeReading(ctx); err != nil {
+				return nil, errors.Annotate(err, "reconnect")
+			}
+			continue
+		}
+		if r.reachedStop(eventTimestamp(connBuff)) {
+			r.stopped = true
+			return nil, ErrEndOfRange
+		}
+
+		evt, err := r.decodeEvent(connBuff, false)
+		if err != nil {
+			if _, ok := err.(*ChecksumError); ok && r.canReconnect() {
+				// Event might have been damaged in transit, read it again
+				if err := r.resumeReading(ctx); err != nil {
+					return nil, errors.Annotate(err, "reconnect")
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		if ss, ok := r.src.(semiSyncSource); ok {
+			evt.AckRequested = ss.AckRequested()
+		}
+		if evt.Header.Type == binlog.EventTypeTransactionPayload {
+			r.payloadAck = evt.AckRequested
+		}
+		if evt.Header.Type == binlog.EventTypeTransactionPayload || r.isDelivered() {
+			// Embedded events are delivered instead of the payload event
+			continue
+		}
+		if isHeartbeat(evt.Header.Type) {
+			// Heartbeats only update the position
+			continue
+		}
+		return evt, nil
+	}
+}
+
+// readPacket reads next packet from the source. When heartbeats are enabled
+// the connection is considered lost if master stays silent for too long.
+func (r *Reader) readPacket(ctx context.Context) ([]byte, error) {
+	if r.conf.HeartbeatPeriod <= 0 {
+		return r.src.ReadPacket(ctx)
+	}
+
+	deadline := time.Now().Add(heartbeatTimeoutFactor * r.conf.HeartbeatPeriod)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		// Caller's deadline comes first
+		return r.src.ReadPacket(ctx)
 	}
+	hctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	connBuff, err := r.src.ReadPacket(hctx)
+	if err != nil && ctx.Err() == nil && isTimeout(err) {
+		return nil, ErrHeartbeatTimeout
+	}
+	return connBuff, err
+}
+
+// isDelivered returns true if the reader was reconnected and current event was
+// delivered before the connection was lost.
+func (r *Reader) isDelivered() bool {
+	if r.resume != nil && r.state.File != "" {
+		if r.state.File == r.resume.File && r.state.Offset <= r.resume.Offset {
+			return true
+		}
+		r.resume = nil
+	}
+	return false
+}
 
+// decodeEvent decodes event header and updates reader state. Embedded events
+// come from a transaction payload event, they are not checksummed and their
+// positions are not tracked.
+func (r *Reader) decodeEvent(connBuff []byte, embedded bool) (*Event, error) {
 	evt := Event{Format: r.format, Offset: r.state.Offset}
 	if err := evt.Header.Decode(connBuff, r.format); err != nil {
 		return nil, errors.Annotate(err, "decode event header")
 	}
-	if evt.Header.NextOffset > 0 {
-		r.state.Offset = uint64(evt.Header.NextOffset)
-	}
 
 	evt.Buffer = connBuff[r.format.HeaderLen():]
 	csa := r.format.ServerDetails.ChecksumAlgorithm
-	if evt.Header.Type != binlog.EventTypeFormatDescription && csa == binlog.ChecksumAlgorithmCRC32 {
-		// Remove trailing CRC32 checksum, we're not going to verify it
+	if !embedded && evt.Header.Type != binlog.EventTypeFormatDescription && csa == binlog.ChecksumAlgorithmCRC32 {
+		if r.conf.VerifyChecksum {
+			if err := r.verifyChecksum(connBuff); err != nil {
+				return nil, err
+			}
+		}
+		// Remove trailing CRC32 checksum
+		evt.Buffer = evt.Buffer[:len(evt.Buffer)-4]
+	}
+	if r.format.Version == 0 && evt.Header.Type == binlog.EventTypeRotate && r.srcChecksum() {
+		// Fake rotate event is sent before the format description event. It
+		// carries a checksum if checksums were requested by the replica
 		evt.Buffer = evt.Buffer[:len(evt.Buffer)-4]
 	}
 
@@ -90,6 +357,13 @@ func (r *Reader) ReadEvent(ctx context.Context) (*Event, error) {
 		if err := fde.Decode(evt.Buffer); err != nil {
 			return nil, errors.Annotate(err, "decode format description event")
 		}
+		// Format description event is checksummed using the algorithm it
+		// describes
+		if r.conf.VerifyChecksum && fde.ServerDetails.ChecksumAlgorithm == binlog.ChecksumAlgorithmCRC32 {
+			if err := r.verifyChecksum(connBuff); err != nil {
+				return nil, err
+			}
+		}
 		r.format = fde.FormatDescription
 		evt.Format = fde.FormatDescription
 
@@ -113,9 +387,16 @@ func (r *Reader) ReadEvent(ctx context.Context) (*Event, error) {
 		binlog.EventTypeUpdateRowsV0,
 		binlog.EventTypeUpdateRowsV1,
 		binlog.EventTypeUpdateRowsV2,
+		binlog.EventTypePartialUpdateRows,
 		binlog.EventTypeDeleteRowsV0,
 		binlog.EventTypeDeleteRowsV1,
-		binlog.EventTypeDeleteRowsV2:
+		binlog.EventTypeDeleteRowsV2,
+		binlog.EventTypeMariaDBWriteRowsCompressedV1,
+		binlog.EventTypeMariaDBUpdateRowsCompressedV1,
+		binlog.EventTypeMariaDBDeleteRowsCompressedV1,
+		binlog.EventTypeMariaDBWriteRowsCompressed,
+		binlog.EventTypeMariaDBUpdateRowsCompressed,
+		binlog.EventTypeMariaDBDeleteRowsCompressed:
 
 		re := binlog.RowsEvent{Type: evt.Header.Type}
 		tableID, flags := re.PeekTableIDAndFlags(evt.Buffer, r.format)
@@ -131,31 +412,209 @@ func (r *Reader) ReadEvent(ctx context.Context) (*Event, error) {
 			// Clear table map
 			r.initTableMap()
 		}
-	case binlog.EventTypeQuery:
-		// Can be decoded by the receiver
+	case binlog.EventTypeQuery, binlog.EventTypeMariaDBQueryCompressed:
+		// Can be decoded by the receiver, transaction boundaries are tracked
+		// here
+		qe, err := evt.DecodeQuery()
+		if err != nil {
+			return nil, errors.Annotate(err, "decode query event")
+		}
+		switch {
+		case bytes.EqualFold(qe.Query, []byte("BEGIN")):
+			r.inTx = true
+		case !r.inTx,
+			bytes.EqualFold(qe.Query, []byte("COMMIT")),
+			bytes.EqualFold(qe.Query, []byte("ROLLBACK")):
+			r.commitTransaction()
+		}
+
 	case binlog.EventTypeXID:
 		// Can be decoded by the receiver
+		r.commitTransaction()
+
 	case binlog.EventTypeGTID:
-		// TODO: Add support
+		var ge binlog.GTIDEvent
+		if err := ge.Decode(evt.Buffer); err != nil {
+			return nil, errors.Annotate(err, "decode gtid event")
+		}
+		r.gtid = &ge
+
+	case binlog.EventTypeAnonymousGTID:
+		// Anonymous GTID event only marks the beginning of a transaction
+		r.gtid = &binlog.GTIDEvent{}
+
+	case binlog.EventTypePreviousGTIDs:
+		var pge binlog.PreviousGTIDsEvent
+		if err := pge.Decode(evt.Buffer); err != nil {
+			return nil, errors.Annotate(err, "decode previous gtids event")
+		}
+		r.gtids.Union(pge.Set)
+
+	case binlog.EventTypeMariaDBGTID:
+		var ge binlog.MariaDBGTIDEvent
+		if err := ge.Decode(evt.Buffer, evt.Header.ServerID); err != nil {
+			return nil, errors.Annotate(err, "decode mariadb gtid event")
+		}
+		// MariaDB GTID event replaces BEGIN query, standalone transactions
+		// end with the next query
+		r.gtid = &binlog.GTIDEvent{}
+		r.mariaGTID = &ge.GTID
+		r.inTx = !ge.IsStandalone()
+
+	case binlog.EventTypeMariaDBGTIDList:
+		var gle binlog.MariaDBGTIDListEvent
+		if err := gle.Decode(evt.Buffer); err != nil {
+			return nil, errors.Annotate(err, "decode mariadb gtid list event")
+		}
+		for _, g := range gle.GTIDs {
+			if cur, ok := r.mariaGTIDs.GTID(g.DomainID); !ok || cur.SequenceNumber < g.SequenceNumber {
+				r.mariaGTIDs.Update(g)
+			}
+		}
+
+	case binlog.EventTypeHeartbeet, binlog.EventTypeHeartbeatV2:
+		var he binlog.HeartbeatEvent
+		var err error
+		if evt.Header.Type == binlog.EventTypeHeartbeet {
+			err = he.Decode(evt.Buffer, evt.Header)
+		} else {
+			err = he.DecodeV2(evt.Buffer)
+		}
+		if err != nil {
+			return nil, errors.Annotate(err, "decode heartbeat event")
+		}
+		// Heartbeat is sent in between transactions
+		if he.Position.File != "" {
+			r.state = he.Position
+		}
+
+	case binlog.EventTypeTransactionPayload:
+		var tpe binlog.TransactionPayloadEvent
+		if err := tpe.Decode(evt.Buffer); err != nil {
+			return nil, errors.Annotate(err, "decode transaction payload event")
+		}
+		events, err := tpe.Events()
+		if err != nil {
+			return nil, errors.Annotate(err, "decompress transaction payload")
+		}
+		r.payload = events
+		r.payloadEnd = uint64(evt.Header.NextOffset)
+	}
+
+	switch {
+	case embedded:
+		if len(r.payload) == 0 {
+			r.state.Offset = r.payloadEnd
+		}
+	case evt.Header.Type == binlog.EventTypeTransactionPayload && len(r.payload) > 0:
+		// Position is advanced after the last embedded event
+	case evt.Header.NextOffset > 0 && evt.Header.Type != binlog.EventTypeRotate && !isHeartbeat(evt.Header.Type):
+		r.state.Offset = uint64(evt.Header.NextOffset)
+	}
+	if r.gtid == nil && !r.inTx {
+		r.safepoint = r.state
 	}
+	// Event that completes the range is still delivered
+	r.stopped = r.stopped || r.reachedStop(0)
 
-	return &evt, err
+	return &evt, nil
 }
 
-// State returns current position in the binary log.
-func (r *Reader) State() binlog.Position {
-	return r.state
+// State returns current position in the binary log and a set of executed
+// transactions.
+func (r *Reader) State() State {
+	return State{
+		Position:      r.state,
+		ExecutedGTIDs: r.gtids.Clone(),
+		MariaDBGTIDs:  r.mariaGTIDs.Clone(),
+	}
+}
+
+// Safepoint returns the position of the last transaction boundary, it is safe
+// to start reading from it.
+func (r *Reader) Safepoint() binlog.Position {
+	return r.safepoint
+}
+
+// Ack acknowledges that events up to the current position were received. It
+// should be called once an event with AckRequested flag is processed.
+func (r *Reader) Ack() error {
+	ss, ok := r.src.(semiSyncSource)
+	if !ok {
+		return driver.ErrSemiSyncUnsupported
+	}
+	return ss.SemiSyncAck(r.state)
 }
 
 // Close underlying database connection.
 func (r *Reader) Close() error {
-	return r.conn.Close()
+	return r.src.Close()
 }
 
 func (r *Reader) initTableMap() {
 	r.tableMap = make(map[uint64]binlog.TableDescription)
 }
 
+// verifyChecksum compares CRC32 checksum stored in the last 4 bytes of the
+// event with the one calculated from its contents.
+func (r *Reader) verifyChecksum(connBuff []byte) error {
+	if len(connBuff) < r.format.HeaderLen()+4 {
+		return binlog.ErrInvalidHeader
+	}
+	n := len(connBuff) - 4
+	exp := mysql.DecodeUint32(connBuff[n:])
+	act := crc32.ChecksumIEEE(connBuff[:n])
+	if exp != act {
+		return &ChecksumError{Position: r.state, Expected: exp, Actual: act}
+	}
+	return nil
+}
+
+// srcChecksum returns true if the source has negotiated checksums for events
+// that are sent before the format description event.
+func (r *Reader) srcChecksum() bool {
+	cs, ok := r.src.(checksumSource)
+	return ok && cs.Checksum()
+}
+
+// commitTransaction adds the GTID of the current transaction to the executed
+// set.
+func (r *Reader) commitTransaction() {
+	r.lastGTID = ""
+	if r.gtid != nil && r.gtid.GNO > 0 {
+		r.gtids.Add(r.gtid.SID, r.gtid.GNO)
+		r.lastGTID = fmt.Sprintf("%s:%d", r.gtid.SID, r.gtid.GNO)
+	}
+	if r.mariaGTID != nil {
+		r.mariaGTIDs.Update(*r.mariaGTID)
+		r.lastGTID = r.mariaGTID.String()
+	}
+	r.gtid = nil
+	r.mariaGTID = nil
+	r.inTx = false
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for event at %s:%d: expected %08x, got %08x",
+		e.Position.File, e.Position.Offset, e.Expected, e.Actual)
+}
+
+// DecodeQuery decodes buffer into a query event. Query of a compressed event
+// is decompressed.
+func (e Event) DecodeQuery() (binlog.QueryEvent, error) {
+	var qe binlog.QueryEvent
+	var err error
+	switch e.Header.Type {
+	case binlog.EventTypeQuery:
+		err = qe.Decode(e.Buffer)
+	case binlog.EventTypeMariaDBQueryCompressed:
+		err = qe.DecodeCompressed(e.Buffer)
+	default:
+		err = errors.New("invalid query event")
+	}
+	return qe, err
+}
+
 // DecodeRows decodes buffer into a rows event.
 func (e Event) DecodeRows() (binlog.RowsEvent, error) {
 	re := binlog.RowsEvent{Type: e.Header.Type}
@@ -165,3 +624,7 @@ func (e Event) DecodeRows() (binlog.RowsEvent, error) {
 	err := re.Decode(e.Buffer, e.Format, *e.Table)
 	return re, err
 }
+
+func isHeartbeat(et binlog.EventType) bool {
+	return et == binlog.EventTypeHeartbeet || et == binlog.EventTypeHeartbeatV2
+}
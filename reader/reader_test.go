@@ -0,0 +1,140 @@
+package reader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestFakeRotateEvent(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	for _, checksum := range []bool{true, false} {
+		events := [][]byte{
+			testFormatDescriptionEvent(),
+			testGTIDEvent(sid, 1),
+			testQueryEvent("testdb", "BEGIN"),
+			testXIDEvent(100),
+		}
+		setTestOffsets(events...)
+
+		// Fake rotate event doesn't have a position, checksum is only
+		// appended if it was requested
+		rotate := testRotateEvent("mysql-bin.000003")
+		if checksum {
+			setTestChecksum(rotate)
+		} else {
+			rotate = rotate[:len(rotate)-4]
+			mysql.EncodeUint32(rotate[9:], uint32(len(rotate)))
+		}
+		packets := append([][]byte{rotate}, events...)
+
+		// Dump with GTID positioning starts with an unknown position
+		r := NewFromSource(&testPacketSource{packets: append(packets, nil), checksum: checksum})
+		readTestEventTypes(t, r)
+		exp := binlog.Position{File: "mysql-bin.000003", Offset: uint64(testFileSize(events...))}
+		if r.State().Position != exp {
+			t.Errorf("Expected position %v with checksum %v, got %v", exp, checksum, r.State().Position)
+		}
+		if r.Safepoint() != exp {
+			t.Errorf("Expected safepoint %v with checksum %v, got %v", exp, checksum, r.Safepoint())
+		}
+	}
+}
+
+func TestAnonymousGTIDEvents(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testAnonymousGTIDEvent(),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(100),
+		testAnonymousGTIDEvent(),
+		testQueryEvent("testdb", "CREATE TABLE bar (id INT)"),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	r := NewFromSource(src)
+	ctx := context.Background()
+
+	for i := range events {
+		if _, err := r.ReadEvent(ctx); err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		// Safepoint stays at the beginning of a transaction until it ends
+		var exp uint64
+		switch i {
+		case 0, 1, 2, 3:
+			exp = uint64(testFileSize(events[:1]...))
+		case 4, 5:
+			exp = uint64(testFileSize(events[:5]...))
+		default:
+			exp = uint64(testFileSize(events...))
+		}
+		if r.Safepoint().Offset != exp {
+			t.Errorf("Expected safepoint at %d after event %d, got %d", exp, i, r.Safepoint().Offset)
+		}
+	}
+	if !r.State().ExecutedGTIDs.IsEmpty() {
+		t.Errorf("Expected executed GTID set to be empty, got %q", r.State().ExecutedGTIDs.String())
+	}
+}
+
+func testAnonymousGTIDEvent() []byte {
+	evt := testGTIDEvent(binlog.SID{}, 0)
+	evt[4] = byte(binlog.EventTypeAnonymousGTID)
+	return evt
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+	}
+	data := testFileContents(events...)
+	// Corrupt the last event
+	data[len(data)-5] ^= 0xFF
+
+	dir, err := ioutil.TempDir("", "bocadillo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mysql-bin.000001")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewFromFile(FileConfig{File: path, VerifyChecksum: true})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+
+	// Fake rotate event is followed by two intact events
+	for i := 0; i < 3; i++ {
+		if _, err := r.ReadEvent(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	_, err = r.ReadEvent(ctx)
+	cerr, ok := errors.Cause(err).(*ChecksumError)
+	if !ok {
+		t.Fatalf("Expected checksum error, got %v", err)
+	}
+	if exp := uint64(testFileSize(events[:2]...)); cerr.Position.Offset != exp {
+		t.Errorf("Expected error at offset %d, got %d", exp, cerr.Position.Offset)
+	}
+	if cerr.Expected == cerr.Actual {
+		t.Errorf("Expected checksums to differ, got %+v", cerr)
+	}
+}
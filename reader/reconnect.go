@@ -0,0 +1,141 @@
+package reader
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+// ReconnectPolicy describes how the reader recovers from connection failures.
+type ReconnectPolicy struct {
+	// MaxAttempts limits the number of consecutive reconnect attempts. Zero
+	// means there's no limit.
+	MaxAttempts int
+	// MinBackoff is a delay before the first attempt. It is doubled after each
+	// failed attempt until it reaches MaxBackoff, which is one minute if not
+	// set. Delays shorter than 100ms are extended so that the server is not
+	// flooded with connections.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Jitter is a fraction of the delay that is randomly added to or
+	// subtracted from it, a value between 0 and 1.
+	Jitter float64
+}
+
+const (
+	// minReconnectBackoff is the shortest delay before a reconnect attempt.
+	minReconnectBackoff = 100 * time.Millisecond
+	// defaultMaxReconnectBackoff is the longest delay before a reconnect
+	// attempt unless the policy sets one.
+	defaultMaxReconnectBackoff = time.Minute
+)
+
+// reconnectSource establishes a new connection. It is replaced in tests.
+var reconnectSource = NewConnSource
+
+// resumeReading establishes a new connection and restarts binary log dump from
+// the last transaction boundary. Format description and table map are
+// rebuilt from the events that follow.
+func (r *Reader) resumeReading(ctx context.Context) error {
+	r.src.Close()
+	if r.resume == nil {
+		pos := r.state
+		r.resume = &pos
+	}
+
+	sc := r.conf
+	sc.File = r.safepoint.File
+	sc.Offset = uint32(r.safepoint.Offset)
+	if sc.GTIDSet != "" {
+		sc.GTIDSet = r.gtids.String()
+	}
+	if sc.MariaDBGTIDSet != "" {
+		sc.MariaDBGTIDSet = r.mariaGTIDs.String()
+	}
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-time.After(r.reconnect.backoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		src, err := reconnectSource(r.dsn, sc)
+		if err == nil {
+			r.src = src
+			break
+		}
+		if r.reconnect.MaxAttempts > 0 && attempt+1 >= r.reconnect.MaxAttempts {
+			return err
+		}
+	}
+
+	r.state = r.safepoint
+	r.format = binlog.FormatDescription{}
+	r.gtid = nil
+	r.mariaGTID = nil
+	r.inTx = false
+	r.payload = nil
+	r.initTableMap()
+	return nil
+}
+
+// canReconnect returns true if reconnect is enabled and the reader is
+// connected to a server.
+func (r *Reader) canReconnect() bool {
+	return r.reconnect != nil && r.dsn != ""
+}
+
+// backoff returns a delay before the given attempt.
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	if d < minReconnectBackoff {
+		d = minReconnectBackoff
+	}
+	limit := p.MaxBackoff
+	if limit == 0 {
+		limit = defaultMaxReconnectBackoff
+	}
+	for i := 0; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	if d < minReconnectBackoff {
+		d = minReconnectBackoff
+	}
+	return d
+}
+
+// isTimeout returns true if the error is caused by a read timeout.
+func isTimeout(err error) bool {
+	err = errors.Cause(err)
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// isConnectionError returns true if the error is caused by a connection
+// failure. Timeouts, cancellations and errors reported by the server are not
+// considered failures.
+func isConnectionError(err error) bool {
+	err = errors.Cause(err)
+	if err == context.DeadlineExceeded || err == context.Canceled || driver.IsServerError(err) {
+		return false
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	p := ReconnectPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	exp := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, d := range exp {
+		if b := p.backoff(i); b != d {
+			t.Errorf("Expected backoff %v before attempt %d, got %v", d, i, b)
+		}
+	}
+
+	// Zero delay would make reconnects flood the server
+	var zero ReconnectPolicy
+	if b := zero.backoff(0); b != minReconnectBackoff {
+		t.Errorf("Expected backoff %v, got %v", minReconnectBackoff, b)
+	}
+	// Doubling stops at the default limit instead of overflowing
+	if b := zero.backoff(1000); b != defaultMaxReconnectBackoff {
+		t.Errorf("Expected backoff %v, got %v", defaultMaxReconnectBackoff, b)
+	}
+
+	p = ReconnectPolicy{MinBackoff: time.Second, MaxBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if b := p.backoff(i); b < 500*time.Millisecond || b > 1500*time.Millisecond {
+			t.Fatalf("Expected backoff within jitter range, got %v", b)
+		}
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err error
+		exp bool
+	}{
+		{io.ErrUnexpectedEOF, true},
+		{errors.Annotate(&net.OpError{Op: "read", Err: io.EOF}, "read next event"), true},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+		{&net.DNSError{IsTimeout: true}, false},
+	}
+	for _, test := range tests {
+		if res := isConnectionError(test.err); res != test.exp {
+			t.Errorf("Expected %v to be a connection error: %v", test.err, test.exp)
+		}
+	}
+}
+
+// testFailingSource returns given packets and fails afterwards.
+type testFailingSource struct {
+	testPacketSource
+	err error
+}
+
+func (s *testFailingSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	if len(s.packets) == 0 {
+		return nil, s.err
+	}
+	return s.testPacketSource.ReadPacket(ctx)
+}
+
+func TestResumeReading(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testGTIDEvent(sid, 1),
+		testQueryEvent("testdb", "BEGIN"),
+		testXIDEvent(100),
+		testGTIDEvent(sid, 2),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(101),
+	}
+	setTestOffsets(events...)
+	fakeRotate := func(offset uint64) []byte {
+		evt := testRotateEvent("mysql-bin.000001")
+		mysql.EncodeUint64(evt[19:], offset)
+		setTestChecksum(evt)
+		return evt
+	}
+
+	// Connection is lost in the middle of the second transaction
+	first := append([][]byte{fakeRotate(4)}, events[:6]...)
+	r := NewFromSource(&testFailingSource{testPacketSource{packets: first, checksum: true}, io.ErrUnexpectedEOF})
+	r.dsn = "test"
+	r.EnableReconnect(ReconnectPolicy{MaxAttempts: 1})
+
+	// Dump is restarted from the last transaction boundary
+	safepoint := uint64(testFileSize(events[:4]...))
+	defer func(orig func(string, driver.Config) (Source, error)) { reconnectSource = orig }(reconnectSource)
+	reconnectSource = func(dsn string, sc driver.Config) (Source, error) {
+		if sc.Offset != uint32(safepoint) {
+			t.Errorf("Expected dump to be resumed at %d, got %d", safepoint, sc.Offset)
+		}
+		second := append([][]byte{fakeRotate(safepoint), events[0]}, events[4:]...)
+		return &testPacketSource{packets: append(second, nil), checksum: true}, nil
+	}
+
+	ctx := context.Background()
+	for range first {
+		if _, err := r.ReadEvent(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Events that were delivered before the connection was lost are skipped
+	evt, err := r.ReadEvent(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := uint64(testFileSize(events[:7]...)); uint64(evt.Header.NextOffset) != exp {
+		t.Errorf("Expected event ending at %d to be delivered, got %d", exp, evt.Header.NextOffset)
+	}
+	if types := readTestEventTypes(t, r); len(types) != 1 || types[0] != binlog.EventTypeXID {
+		t.Errorf("Expected XID event to follow, got %v", types)
+	}
+	if exp := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-2"; r.State().ExecutedGTIDs.String() != exp {
+		t.Errorf("Expected executed GTID set %q, got %q", exp, r.State().ExecutedGTIDs.String())
+	}
+}
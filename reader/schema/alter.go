@@ -0,0 +1,540 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ColumnChange is a change of a table column made by an ALTER TABLE statement.
+// Changes of primary and unique keys are column changes as well.
+type ColumnChange struct {
+	Type ColumnChangeType
+	// Name of an existing column, it is empty for added columns. It is the
+	// name of an index for dropped keys.
+	Name string
+	// Column is a new column definition. Only the name is set for renamed
+	// columns.
+	Column Column
+	// First and After define the new position of the column. Column keeps
+	// its position if neither is set, added columns are appended.
+	First bool
+	After string
+	// IfExists is set for changes made with IF [NOT] EXISTS clause. Such
+	// changes are skipped if the column is missing, or if it already exists
+	// for added columns.
+	IfExists bool
+	// Key is the name of an added unique key. Unnamed keys are named after
+	// their first column.
+	Key string
+}
+
+// ColumnChangeType is a type of column change.
+type ColumnChangeType int
+
+// Column change types.
+const (
+	// ColumnAdd adds a new column.
+	ColumnAdd ColumnChangeType = iota + 1
+	// ColumnDrop drops a column.
+	ColumnDrop
+	// ColumnModify changes column definition, name stays the same.
+	ColumnModify
+	// ColumnChangeDef changes column name and definition.
+	ColumnChangeDef
+	// ColumnRename changes column name.
+	ColumnRename
+	// ColumnAddPrimaryKey makes column a part of the primary key.
+	ColumnAddPrimaryKey
+	// ColumnAddUniqueKey makes column a part of a unique key.
+	ColumnAddUniqueKey
+	// ColumnDropPrimaryKey drops the primary key.
+	ColumnDropPrimaryKey
+	// ColumnDropKey drops an index or a constraint.
+	ColumnDropKey
+	// ColumnSetDefault changes column default value. Only the default is set
+	// in the column definition, it is nil for dropped defaults.
+	ColumnSetDefault
+)
+
+var (
+	// ErrUnknownColumn is returned when a column change refers to a column
+	// that is missing from the table definition.
+	ErrUnknownColumn = errors.New("Unknown column")
+	// ErrUnknownKey is returned when an index is dropped and it can't be
+	// told if it was a unique key because key names are not known.
+	ErrUnknownKey = errors.New("Unknown key")
+)
+
+// Keywords that begin a table element or an ALTER TABLE specification that is
+// not a column.
+var nonColumnKeywords = []string{
+	"CONSTRAINT", "CHECK", "FOREIGN", "FULLTEXT", "INDEX", "KEY", "PARTITION",
+	"PERIOD", "PRIMARY", "SPATIAL", "SYSTEM", "UNIQUE",
+}
+
+// alter returns a copy of the table with given changes applied.
+func (t Table) alter(changes []ColumnChange) (Table, error) {
+	cols := append([]Column{}, t.columns...)
+	keys := newKeyNames(cols)
+	for _, ch := range changes {
+		switch ch.Type {
+		case ColumnDropPrimaryKey:
+			for i := range cols {
+				cols[i].PrimaryKey = false
+			}
+			continue
+		case ColumnDropKey:
+			// Dropped index is not a unique key unless a column is a part of
+			// it, that can't be told if key names are missing
+			var dropped bool
+			for i := range cols {
+				dropped = cols[i].dropUniqueKey(ch.Name) || dropped
+			}
+			if !dropped && hasUnnamedUniqueKeys(cols) {
+				return t, ErrUnknownKey
+			}
+			continue
+		}
+
+		i := -1
+		if ch.Type != ColumnAdd {
+			if i = columnIndex(cols, ch.Name); i < 0 {
+				if ch.IfExists {
+					continue
+				}
+				return t, ErrUnknownColumn
+			}
+		} else if ch.IfExists && columnIndex(cols, ch.Column.Name) >= 0 {
+			continue
+		}
+
+		col := ch.Column
+		switch ch.Type {
+		case ColumnDrop:
+			cols = append(cols[:i], cols[i+1:]...)
+			continue
+		case ColumnRename:
+			cols[i].Name = col.Name
+			continue
+		case ColumnSetDefault:
+			cols[i].Default = col.Default
+			continue
+		case ColumnAddPrimaryKey:
+			cols[i].PrimaryKey, cols[i].Nullable = true, false
+			continue
+		case ColumnAddUniqueKey:
+			name := ch.Key
+			if name == "" {
+				name = cols[i].Name
+			}
+			cols[i].addUniqueKey(keys.add(name))
+			continue
+		case ColumnAdd:
+			if col.UniqueKey {
+				col.UniqueKeys = []string{keys.next(col.Name)}
+			}
+			i = len(cols)
+		default:
+			// Modified column keeps its keys and is moved
+			if col.UniqueKey {
+				col.UniqueKeys = []string{keys.next(col.Name)}
+			}
+			if len(cols[i].UniqueKeys) > 0 {
+				col.UniqueKeys = append(append([]string{}, cols[i].UniqueKeys...), col.UniqueKeys...)
+			}
+			col.PrimaryKey = col.PrimaryKey || cols[i].PrimaryKey
+			col.UniqueKey = col.UniqueKey || cols[i].UniqueKey
+			if col.PrimaryKey {
+				col.Nullable = false
+			}
+			cols = append(cols[:i], cols[i+1:]...)
+		}
+
+		switch {
+		case ch.First:
+			i = 0
+		case ch.After != "":
+			if i = columnIndex(cols, ch.After); i < 0 {
+				return t, ErrUnknownColumn
+			}
+			i++
+		}
+		cols = append(cols, Column{})
+		copy(cols[i+1:], cols[i:])
+		cols[i] = col
+	}
+	return Table{columns: cols}, nil
+}
+
+// addUniqueKey makes the column a part of the unique key with given name.
+// List of names is copied because it could be shared with another table.
+func (c *Column) addUniqueKey(name string) {
+	for _, k := range c.UniqueKeys {
+		if strings.EqualFold(k, name) {
+			return
+		}
+	}
+	c.UniqueKey = true
+	c.UniqueKeys = append(append([]string{}, c.UniqueKeys...), name)
+}
+
+// dropUniqueKey removes the column from the unique key with given name. True
+// is returned if the column was a part of it.
+func (c *Column) dropUniqueKey(name string) bool {
+	for i, k := range c.UniqueKeys {
+		if strings.EqualFold(k, name) {
+			keys := append([]string{}, c.UniqueKeys[:i]...)
+			c.UniqueKeys = append(keys, c.UniqueKeys[i+1:]...)
+			c.UniqueKey = len(c.UniqueKeys) > 0
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnnamedUniqueKeys returns true if a column is a part of a unique key
+// which name is not known.
+func hasUnnamedUniqueKeys(cols []Column) bool {
+	for _, col := range cols {
+		if col.UniqueKey && len(col.UniqueKeys) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// keyNames generates names of unique keys the way MySQL does it: a key that
+// is named after a column that already names another key gets a numeric
+// suffix. Parts of a key added by a single statement share the name.
+type keyNames struct {
+	taken map[string]bool
+	added map[string]string
+}
+
+func newKeyNames(cols []Column) *keyNames {
+	kn := &keyNames{taken: make(map[string]bool), added: make(map[string]string)}
+	for _, col := range cols {
+		for _, k := range col.UniqueKeys {
+			kn.taken[strings.ToLower(k)] = true
+		}
+	}
+	return kn
+}
+
+// add returns the name of a key with given base name that could have more
+// than one part.
+func (kn *keyNames) add(base string) string {
+	if name, ok := kn.added[strings.ToLower(base)]; ok {
+		return name
+	}
+	name := kn.next(base)
+	kn.added[strings.ToLower(base)] = name
+	return name
+}
+
+// next returns the name of a new key with given base name.
+func (kn *keyNames) next(base string) string {
+	name := base
+	for i := 2; kn.taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	kn.taken[strings.ToLower(name)] = true
+	return name
+}
+
+// columnIndex returns the index of a column with given name. Column names are
+// case insensitive.
+func columnIndex(cols []Column, name string) int {
+	for i, col := range cols {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+//
+// Parser
+//
+
+// columnChanges parses column changes of an ALTER TABLE specification. Specs
+// that don't change columns are skipped.
+//
+// ADD [COLUMN] [IF NOT EXISTS] name definition [FIRST | AFTER name]
+// ADD [COLUMN] [IF NOT EXISTS] (name definition, ...)
+// ADD key_definition
+// DROP [COLUMN] [IF EXISTS] name
+// DROP PRIMARY KEY
+// DROP {INDEX | KEY | CONSTRAINT} [IF EXISTS] name
+// MODIFY [COLUMN] [IF EXISTS] name definition [FIRST | AFTER name]
+// CHANGE [COLUMN] [IF EXISTS] old_name new_name definition [FIRST | AFTER name]
+// ALTER [COLUMN] name {SET DEFAULT value | DROP DEFAULT}
+// RENAME COLUMN old_name TO new_name
+func (p *parser) columnChanges() []ColumnChange {
+	switch {
+	case p.keyword("ADD"):
+		p.keyword("COLUMN")
+		if p.isKeyword(nonColumnKeywords...) {
+			return p.keyDefinition()
+		}
+		ifNotExists := p.keyword("IF", "NOT", "EXISTS")
+		if p.punct('(') {
+			var changes []ColumnChange
+			for _, def := range p.tableElements() {
+				if ch, ok := def.columnDefinition(ColumnAdd); ok {
+					ch.IfExists = ifNotExists
+					changes = append(changes, ch)
+				}
+			}
+			return changes
+		}
+		ch, ok := p.columnDefinition(ColumnAdd)
+		ch.IfExists = ifNotExists
+		return p.changes(ch, ok)
+	case p.keyword("DROP"):
+		switch {
+		case p.keyword("PRIMARY", "KEY"):
+			return []ColumnChange{{Type: ColumnDropPrimaryKey}}
+		case p.keyword("INDEX"), p.keyword("KEY"), p.keyword("CONSTRAINT"):
+			ifExists := p.keyword("IF", "EXISTS")
+			name, ok := p.ident()
+			if strings.EqualFold(name, "PRIMARY") {
+				return []ColumnChange{{Type: ColumnDropPrimaryKey}}
+			}
+			return p.changes(ColumnChange{Type: ColumnDropKey, Name: name, IfExists: ifExists}, ok)
+		}
+		p.keyword("COLUMN")
+		if p.isKeyword(nonColumnKeywords...) {
+			return nil
+		}
+		ifExists := p.keyword("IF", "EXISTS")
+		name, ok := p.ident()
+		return p.changes(ColumnChange{Type: ColumnDrop, Name: name, IfExists: ifExists}, ok)
+	case p.keyword("MODIFY"):
+		p.keyword("COLUMN")
+		ifExists := p.keyword("IF", "EXISTS")
+		name := p.peekIdent()
+		ch, ok := p.columnDefinition(ColumnModify)
+		ch.Name, ch.IfExists = name, ifExists
+		return p.changes(ch, ok)
+	case p.keyword("CHANGE"):
+		p.keyword("COLUMN")
+		ifExists := p.keyword("IF", "EXISTS")
+		name, ok := p.ident()
+		if !ok {
+			return nil
+		}
+		ch, ok := p.columnDefinition(ColumnChangeDef)
+		ch.Name, ch.IfExists = name, ifExists
+		return p.changes(ch, ok)
+	case p.keyword("ALTER"):
+		p.keyword("COLUMN")
+		if p.isKeyword("INDEX", "CHECK", "CONSTRAINT") {
+			return nil
+		}
+		name, ok := p.ident()
+		if !ok {
+			return nil
+		}
+		ch := ColumnChange{Type: ColumnSetDefault, Name: name}
+		switch {
+		case p.keyword("SET", "DEFAULT"):
+			ch.Column.Default = p.defaultValue()
+		case p.keyword("DROP", "DEFAULT"):
+		default:
+			// Visibility is not tracked
+			return nil
+		}
+		return []ColumnChange{ch}
+	case p.keyword("RENAME", "COLUMN"):
+		from, ok := p.ident()
+		if !ok || !p.keyword("TO") {
+			return nil
+		}
+		to, ok := p.ident()
+		return p.changes(ColumnChange{Type: ColumnRename, Name: from, Column: Column{Name: to}}, ok)
+	}
+	return nil
+}
+
+// columnDefinition parses a column definition followed by an optional
+// position.
+func (p *parser) columnDefinition(typ ColumnChangeType) (ColumnChange, bool) {
+	ch := ColumnChange{Type: typ}
+	name, ok := p.ident()
+	if !ok {
+		return ch, false
+	}
+	col := &ch.Column
+	col.Name, col.Nullable = name, true
+	p.dataType(col)
+
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.keyword("UNSIGNED"), p.keyword("ZEROFILL"):
+			// Zero-filled columns are unsigned
+			col.Unsigned = true
+		case p.keyword("NOT", "NULL"):
+			col.Nullable = false
+		case p.keyword("NULL"):
+			col.Nullable = true
+		case p.keyword("DEFAULT"):
+			col.Default = p.defaultValue()
+		case p.keyword("CHARACTER", "SET"), p.keyword("CHARSET"):
+			col.Charset = p.name()
+		case p.keyword("COLLATE"):
+			col.Collation = p.name()
+		case p.keyword("GENERATED", "ALWAYS"), p.keyword("AS"):
+			col.Generated = true
+		case p.keyword("PRIMARY", "KEY"), p.keyword("KEY"):
+			col.PrimaryKey, col.Nullable = true, false
+		case p.keyword("UNIQUE"):
+			p.keyword("KEY")
+			col.UniqueKey = true
+		case p.keyword("REFERENCES"):
+			// Reference options could contain SET NULL and SET DEFAULT
+			for p.pos < len(p.tokens) && !p.isKeyword("FIRST", "AFTER") {
+				p.pos++
+			}
+		case p.keyword("FIRST"):
+			ch.First = true
+		case p.keyword("AFTER"):
+			ch.After = p.name()
+		case p.punct('('):
+			p.skipParens()
+		default:
+			p.pos++
+		}
+	}
+	return ch, true
+}
+
+// keyDefinition parses a primary or a unique key definition into column
+// changes. Other index definitions are skipped.
+//
+// [CONSTRAINT [symbol]] PRIMARY KEY [USING type] (key_part, ...)
+// [CONSTRAINT [symbol]] UNIQUE [INDEX | KEY] [name] [USING type] (key_part, ...)
+func (p *parser) keyDefinition() []ColumnChange {
+	var key string
+	if p.keyword("CONSTRAINT") && !p.isKeyword("PRIMARY", "UNIQUE") {
+		key, _ = p.ident()
+	}
+	var typ ColumnChangeType
+	switch {
+	case p.keyword("PRIMARY", "KEY"):
+		typ = ColumnAddPrimaryKey
+	case p.keyword("UNIQUE"):
+		typ = ColumnAddUniqueKey
+		if !p.keyword("INDEX") {
+			p.keyword("KEY")
+		}
+		// Index name takes precedence over the constraint symbol
+		if name, ok := p.ident(); ok && !strings.EqualFold(name, "USING") {
+			key = name
+		}
+	default:
+		return nil
+	}
+	for p.pos < len(p.tokens) && !p.punct('(') {
+		p.pos++
+	}
+
+	var changes []ColumnChange
+	for _, part := range p.tableElements() {
+		// Functional key parts are skipped
+		name, ok := part.ident()
+		if !ok {
+			continue
+		}
+		ch := ColumnChange{Type: typ, Name: name}
+		if typ == ColumnAddUniqueKey {
+			if key == "" {
+				key = name
+			}
+			ch.Key = key
+		}
+		changes = append(changes, ch)
+	}
+	return changes
+}
+
+// tableElements splits a parenthesized list of column and index definitions
+// into separate parsers. Closing parenthesis is read.
+func (p *parser) tableElements() []*parser {
+	var elems []*parser
+	depth, start := 0, p.pos
+	for ; p.pos < len(p.tokens); p.pos++ {
+		tok := p.tokens[p.pos]
+		if tok.typ != tokenPunct {
+			continue
+		}
+		switch {
+		case tok.val == "(":
+			depth++
+		case tok.val == ")" && depth > 0:
+			depth--
+		case tok.val == ")", tok.val == "," && depth == 0:
+			elems = append(elems, &parser{tokens: p.tokens[start:p.pos], database: p.database})
+			start = p.pos + 1
+			if tok.val == ")" {
+				p.pos++
+				return elems
+			}
+		}
+	}
+	// Unbalanced parentheses
+	return nil
+}
+
+// columns parses column definitions of a table element list. Primary and
+// unique keys are applied to the columns, other index definitions are skipped.
+func columns(elems []*parser) []Column {
+	cols := make([]Column, 0, len(elems))
+	var keys []ColumnChange
+	for _, elem := range elems {
+		if elem.isKeyword(nonColumnKeywords...) {
+			keys = append(keys, elem.keyDefinition()...)
+			continue
+		}
+		if ch, ok := elem.columnDefinition(ColumnAdd); ok {
+			cols = append(cols, ch.Column)
+		}
+	}
+	// Column unique keys are named after columns
+	for i := range cols {
+		if cols[i].UniqueKey {
+			cols[i].UniqueKeys = []string{cols[i].Name}
+		}
+	}
+	if tbl, err := (Table{columns: cols}).alter(keys); err == nil {
+		return tbl.columns
+	}
+	return cols
+}
+
+// changes wraps a single change into a list if it was parsed successfully.
+func (p *parser) changes(ch ColumnChange, ok bool) []ColumnChange {
+	if !ok {
+		return nil
+	}
+	return []ColumnChange{ch}
+}
+
+// name reads an identifier or a string, e.g. a character set name. Empty
+// string is returned if there is none.
+func (p *parser) name() string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].typ != tokenPunct {
+		p.pos++
+		return p.tokens[p.pos-1].val
+	}
+	return ""
+}
+
+// peekIdent returns next identifier without reading it.
+func (p *parser) peekIdent() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].val
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColumnChanges(t *testing.T) {
+	query := "ALTER TABLE foo ADD COLUMN `b` INT UNSIGNED NOT NULL DEFAULT 0 AFTER a, " +
+		"ADD c DECIMAL(10,2) ZEROFILL FIRST, ADD (d TEXT, e ENUM('FIRST', 'x')), " +
+		"DROP COLUMN f, DROP INDEX idx, ADD UNIQUE KEY (a, b), " +
+		"MODIFY g BIGINT COMMENT 'unsigned', CHANGE COLUMN h `i` TINYINT(1) unsigned AFTER `a`, " +
+		"RENAME COLUMN j TO k, ALTER COLUMN l SET DEFAULT 1, ENGINE=InnoDB"
+	ddl, ok := ParseDDL("db", query)
+	if !ok {
+		t.Fatalf("Expected query to be parsed")
+	}
+	exp := []ColumnChange{
+		{Type: ColumnAdd, Column: Column{Name: "b", Type: "int", Unsigned: true, Default: str("0")}, After: "a"},
+		{Type: ColumnAdd, Column: Column{Name: "c", Type: "decimal", Precision: 10, Scale: 2, Unsigned: true, Nullable: true}, First: true},
+		{Type: ColumnAdd, Column: Column{Name: "d", Type: "text", Nullable: true}},
+		{Type: ColumnAdd, Column: Column{Name: "e", Type: "enum", Nullable: true, Values: []string{"FIRST", "x"}}},
+		{Type: ColumnDrop, Name: "f"},
+		{Type: ColumnDropKey, Name: "idx"},
+		{Type: ColumnAddUniqueKey, Name: "a", Key: "a"},
+		{Type: ColumnAddUniqueKey, Name: "b", Key: "a"},
+		{Type: ColumnModify, Name: "g", Column: Column{Name: "g", Type: "bigint", Nullable: true}},
+		{Type: ColumnChangeDef, Name: "h", Column: Column{Name: "i", Type: "tinyint", Length: 1, Unsigned: true, Nullable: true}, After: "a"},
+		{Type: ColumnRename, Name: "j", Column: Column{Name: "k"}},
+		{Type: ColumnSetDefault, Name: "l", Column: Column{Default: str("1")}},
+	}
+	if !reflect.DeepEqual(ddl.ColumnChanges, exp) {
+		t.Errorf("Expected changes:\n%+v\ngot:\n%+v", exp, ddl.ColumnChanges)
+	}
+}
+
+func TestParseColumnChangesIfExists(t *testing.T) {
+	query := "ALTER TABLE foo ADD COLUMN IF NOT EXISTS a INT, ADD IF NOT EXISTS (b INT), " +
+		"DROP COLUMN IF EXISTS c, DROP INDEX IF EXISTS idx, MODIFY IF EXISTS d BIGINT, " +
+		"CHANGE COLUMN IF EXISTS e f TEXT, ALTER COLUMN g DROP DEFAULT, ALTER h SET VISIBLE"
+	ddl, ok := ParseDDL("db", query)
+	if !ok {
+		t.Fatalf("Expected query to be parsed")
+	}
+	exp := []ColumnChange{
+		{Type: ColumnAdd, Column: Column{Name: "a", Type: "int", Nullable: true}, IfExists: true},
+		{Type: ColumnAdd, Column: Column{Name: "b", Type: "int", Nullable: true}, IfExists: true},
+		{Type: ColumnDrop, Name: "c", IfExists: true},
+		{Type: ColumnDropKey, Name: "idx", IfExists: true},
+		{Type: ColumnModify, Name: "d", Column: Column{Name: "d", Type: "bigint", Nullable: true}, IfExists: true},
+		{Type: ColumnChangeDef, Name: "e", Column: Column{Name: "f", Type: "text", Nullable: true}, IfExists: true},
+		{Type: ColumnSetDefault, Name: "g"},
+	}
+	if !reflect.DeepEqual(ddl.ColumnChanges, exp) {
+		t.Errorf("Expected changes:\n%+v\ngot:\n%+v", exp, ddl.ColumnChanges)
+	}
+}
+
+func TestParseCreateTableColumns(t *testing.T) {
+	ddl, _ := ParseDDL("db", "CREATE TABLE foo (id INT UNSIGNED NOT NULL, "+
+		"`name` VARCHAR(10), PRIMARY KEY (id), KEY idx (name(5))) ENGINE=InnoDB")
+	exp := []Column{
+		{Name: "id", Type: "int", Unsigned: true, PrimaryKey: true},
+		{Name: "name", Type: "varchar", Length: 10, Nullable: true},
+	}
+	if !reflect.DeepEqual(ddl.Columns, exp) {
+		t.Errorf("Expected columns %+v, got %+v", exp, ddl.Columns)
+	}
+
+	ddl, _ = ParseDDL("db", "CREATE TABLE foo (id INT) SELECT id, name FROM bar")
+	if ddl.Columns != nil {
+		t.Errorf("Expected columns to be unknown, got %+v", ddl.Columns)
+	}
+}
+
+func TestTableAlter(t *testing.T) {
+	tbl := Table{columns: []Column{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+	names := func(tbl Table) []string {
+		var res []string
+		for _, col := range tbl.columns {
+			res = append(res, col.Name)
+		}
+		return res
+	}
+
+	tests := []struct {
+		change ColumnChange
+		exp    []string
+	}{
+		{ColumnChange{Type: ColumnAdd, Column: Column{Name: "d"}}, []string{"a", "b", "c", "d"}},
+		{ColumnChange{Type: ColumnAdd, Column: Column{Name: "d"}, First: true}, []string{"d", "a", "b", "c"}},
+		{ColumnChange{Type: ColumnAdd, Column: Column{Name: "d"}, After: "A"}, []string{"a", "d", "b", "c"}},
+		{ColumnChange{Type: ColumnDrop, Name: "b"}, []string{"a", "c"}},
+		{ColumnChange{Type: ColumnModify, Name: "b", Column: Column{Name: "b"}}, []string{"a", "b", "c"}},
+		{ColumnChange{Type: ColumnModify, Name: "c", Column: Column{Name: "c"}, First: true}, []string{"c", "a", "b"}},
+		{ColumnChange{Type: ColumnChangeDef, Name: "a", Column: Column{Name: "x"}, After: "c"}, []string{"b", "c", "x"}},
+		{ColumnChange{Type: ColumnRename, Name: "c", Column: Column{Name: "y"}}, []string{"a", "b", "y"}},
+		{ColumnChange{Type: ColumnAdd, Column: Column{Name: "A"}, IfExists: true}, []string{"a", "b", "c"}},
+		{ColumnChange{Type: ColumnDrop, Name: "z", IfExists: true}, []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		res, err := tbl.alter([]ColumnChange{test.change})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(names(res), test.exp) {
+			t.Errorf("Expected %v after %+v, got %v", test.exp, test.change, names(res))
+		}
+	}
+	if !reflect.DeepEqual(names(tbl), []string{"a", "b", "c"}) {
+		t.Errorf("Expected original table not to change, got %v", names(tbl))
+	}
+
+	if _, err := tbl.alter([]ColumnChange{{Type: ColumnDrop, Name: "z"}}); err != ErrUnknownColumn {
+		t.Errorf("Expected unknown column error, got %v", err)
+	}
+	res, _ := tbl.alter([]ColumnChange{{Type: ColumnSetDefault, Name: "b", Column: Column{Default: str("x")}}})
+	if def := res.columns[1].Default; def == nil || *def != "x" {
+		t.Errorf("Expected default to be set, got %v", def)
+	}
+	res, _ = res.alter([]ColumnChange{{Type: ColumnSetDefault, Name: "b"}})
+	if def := res.columns[1].Default; def != nil {
+		t.Errorf("Expected default to be dropped, got %q", *def)
+	}
+}
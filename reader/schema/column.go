@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Data type synonyms are replaced with the names the server reports.
+var typeSynonyms = map[string]string{
+	"bool":      "tinyint",
+	"boolean":   "tinyint",
+	"integer":   "int",
+	"dec":       "decimal",
+	"numeric":   "decimal",
+	"fixed":     "decimal",
+	"real":      "double",
+	"character": "char",
+}
+
+// parseColumnType parses a column type the way it is described in the
+// COLUMN_TYPE column of the INFORMATION_SCHEMA.COLUMNS table, e.g.
+// "decimal(10,2) unsigned" or "enum('a','b')".
+func parseColumnType(typ string) Column {
+	var col Column
+	p := &parser{tokens: lex(typ)}
+	p.dataType(&col)
+	for ; p.pos < len(p.tokens); p.pos++ {
+		if p.tokens[p.pos].is("UNSIGNED") || p.tokens[p.pos].is("ZEROFILL") {
+			col.Unsigned = true
+		}
+	}
+	return col
+}
+
+// dataType parses a data type name followed by optional length, precision
+// and scale or a list of ENUM and SET members.
+func (p *parser) dataType(col *Column) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].typ != tokenWord {
+		return
+	}
+	col.Type = strings.ToLower(p.tokens[p.pos].val)
+	p.pos++
+	if typ, ok := typeSynonyms[col.Type]; ok {
+		col.Type = typ
+	}
+	switch col.Type {
+	case "double":
+		p.keyword("PRECISION")
+	case "serial":
+		// Alias for BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE
+		col.Type, col.Unsigned, col.Nullable, col.UniqueKey = "bigint", true, false, true
+	}
+	if !p.punct('(') {
+		return
+	}
+
+	var params []uint64
+	for _, elem := range p.tableElements() {
+		if len(elem.tokens) == 0 {
+			continue
+		}
+		tok := elem.tokens[0]
+		if tok.typ == tokenString {
+			col.Values = append(col.Values, tok.val)
+			continue
+		}
+		n, _ := strconv.ParseUint(tok.val, 10, 64)
+		params = append(params, n)
+	}
+	if len(params) == 0 {
+		return
+	}
+	switch col.Type {
+	case "decimal", "float", "double":
+		col.Precision = int(params[0])
+		if len(params) > 1 {
+			col.Scale = int(params[1])
+		}
+	case "time", "datetime", "timestamp":
+		col.Precision = int(params[0])
+	default:
+		col.Length = params[0]
+	}
+}
+
+// defaultValue reads a default value. Nil is returned for NULL.
+func (p *parser) defaultValue() *string {
+	if p.pos >= len(p.tokens) || p.keyword("NULL") {
+		return nil
+	}
+	start := p.pos
+	switch {
+	case p.punct('('):
+		// Expression
+		p.skipParens()
+	case p.punct('-'), p.punct('+'):
+		// Signed number
+		if p.pos < len(p.tokens) {
+			p.pos++
+		}
+	case p.tokens[p.pos].typ == tokenString:
+		val := p.tokens[p.pos].val
+		p.pos++
+		return &val
+	default:
+		// Literal or a function call like CURRENT_TIMESTAMP(6)
+		p.pos++
+		if p.punct('(') {
+			p.skipParens()
+		}
+	}
+	val := render(p.tokens[start:p.pos])
+	return &val
+}
+
+// skipParens reads tokens up to and including the parenthesis that closes the
+// one that was just read.
+func (p *parser) skipParens() {
+	for depth := 1; p.pos < len(p.tokens) && depth > 0; p.pos++ {
+		if tok := p.tokens[p.pos]; tok.typ == tokenPunct {
+			switch tok.val {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+		}
+	}
+}
+
+// render turns tokens back into SQL. Whitespace is only kept between words.
+func render(tokens []token) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && tok.typ != tokenPunct && tokens[i-1].typ != tokenPunct {
+			b.WriteByte(' ')
+		}
+		switch tok.typ {
+		case tokenQuoted:
+			b.WriteString("`" + strings.ReplaceAll(tok.val, "`", "``") + "`")
+		case tokenString:
+			b.WriteString("'" + strings.ReplaceAll(tok.val, "'", "''") + "'")
+		default:
+			b.WriteString(tok.val)
+		}
+	}
+	return b.String()
+}
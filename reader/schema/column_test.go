@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColumnDefinitions(t *testing.T) {
+	ddl, _ := ParseDDL("db", "CREATE TABLE foo ("+
+		"id SERIAL, "+
+		"a INT(11) NOT NULL DEFAULT -1 COMMENT 'NULL', "+
+		"b VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT 'it''s', "+
+		"c SET('x', 'y') CHARSET latin1 NULL DEFAULT NULL, "+
+		"d DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6), "+
+		"e DOUBLE PRECISION DEFAULT (RAND() * 2), "+
+		"f INT GENERATED ALWAYS AS (a + 1) VIRTUAL, "+
+		"g INT REFERENCES bar (id) ON DELETE SET NULL, "+
+		"h BOOL NOT NULL UNIQUE, "+
+		"CONSTRAINT pk PRIMARY KEY (a, b(10)), "+
+		"UNIQUE INDEX uniq USING BTREE (c), "+
+		"INDEX idx (d))")
+	exp := []Column{
+		{Name: "id", Type: "bigint", Unsigned: true, UniqueKey: true, UniqueKeys: []string{"id"}},
+		{Name: "a", Type: "int", Length: 11, Default: str("-1"), PrimaryKey: true},
+		{Name: "b", Type: "varchar", Length: 64, Default: str("it's"), Charset: "utf8mb4", Collation: "utf8mb4_bin", PrimaryKey: true},
+		{Name: "c", Type: "set", Nullable: true, Charset: "latin1", Values: []string{"x", "y"}, UniqueKey: true, UniqueKeys: []string{"uniq"}},
+		{Name: "d", Type: "datetime", Precision: 6, Nullable: true, Default: str("CURRENT_TIMESTAMP(6)")},
+		{Name: "e", Type: "double", Nullable: true, Default: str("(RAND()*2)")},
+		{Name: "f", Type: "int", Nullable: true, Generated: true},
+		{Name: "g", Type: "int", Nullable: true},
+		{Name: "h", Type: "tinyint", UniqueKey: true, UniqueKeys: []string{"h"}},
+	}
+	if !reflect.DeepEqual(ddl.Columns, exp) {
+		t.Errorf("Expected columns:\n%+v\ngot:\n%+v", exp, ddl.Columns)
+	}
+}
+
+func TestParseColumnType(t *testing.T) {
+	tests := []struct {
+		typ string
+		exp Column
+	}{
+		{"int(10) unsigned zerofill", Column{Type: "int", Length: 10, Unsigned: true}},
+		{"decimal(10,2)", Column{Type: "decimal", Precision: 10, Scale: 2}},
+		{"enum('a','b''c')", Column{Type: "enum", Values: []string{"a", "b'c"}}},
+		{"timestamp(3)", Column{Type: "timestamp", Precision: 3}},
+		{"bit(8)", Column{Type: "bit", Length: 8}},
+	}
+	for _, test := range tests {
+		if col := parseColumnType(test.typ); !reflect.DeepEqual(col, test.exp) {
+			t.Errorf("Expected %+v for %q, got %+v", test.exp, test.typ, col)
+		}
+	}
+}
+
+func TestTableAlterKeys(t *testing.T) {
+	tbl := Table{columns: []Column{{Name: "a", Nullable: true}, {Name: "b", Nullable: true}}}
+	tbl, err := tbl.alter([]ColumnChange{
+		{Type: ColumnAddPrimaryKey, Name: "a"},
+		{Type: ColumnAddUniqueKey, Name: "b"},
+		{Type: ColumnModify, Name: "a", Column: Column{Name: "a", Type: "bigint", Nullable: true}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := []Column{
+		{Name: "a", Type: "bigint", PrimaryKey: true},
+		{Name: "b", Nullable: true, UniqueKey: true, UniqueKeys: []string{"b"}},
+	}
+	if !reflect.DeepEqual(tbl.columns, exp) {
+		t.Errorf("Expected columns %+v, got %+v", exp, tbl.columns)
+	}
+
+	// Dropped index is not a unique key
+	res, err := tbl.alter([]ColumnChange{{Type: ColumnDropKey, Name: "idx"}})
+	if err != nil || !res.columns[1].UniqueKey {
+		t.Errorf("Expected unique key to remain, got %+v (%v)", res.columns[1], err)
+	}
+	res, err = tbl.alter([]ColumnChange{{Type: ColumnDropKey, Name: "B"}})
+	if err != nil || res.columns[1].UniqueKey || len(res.columns[1].UniqueKeys) > 0 {
+		t.Errorf("Expected unique key to be dropped, got %+v (%v)", res.columns[1], err)
+	}
+	if !tbl.columns[1].UniqueKey {
+		t.Errorf("Expected original table not to change")
+	}
+	// Key names are missing from older definitions
+	legacy := Table{columns: []Column{{Name: "a", UniqueKey: true}}}
+	if _, err := legacy.alter([]ColumnChange{{Type: ColumnDropKey, Name: "idx"}}); err != ErrUnknownKey {
+		t.Errorf("Expected error %v, got %v", ErrUnknownKey, err)
+	}
+	tbl, _ = tbl.alter([]ColumnChange{{Type: ColumnDropPrimaryKey}})
+	if tbl.columns[0].PrimaryKey {
+		t.Errorf("Expected primary key to be dropped")
+	}
+}
+
+func TestTableAlterKeyNames(t *testing.T) {
+	ddl, _ := ParseDDL("db", "CREATE TABLE foo (a INT UNIQUE, b INT, c INT, "+
+		"CONSTRAINT c_uniq UNIQUE (b, c), UNIQUE (a))")
+	tbl := Table{columns: ddl.Columns}
+	keys := func(tbl Table) [][]string {
+		var res [][]string
+		for _, col := range tbl.columns {
+			res = append(res, col.UniqueKeys)
+		}
+		return res
+	}
+	// Unnamed keys are named after their first column
+	if exp := [][]string{{"a", "a_2"}, {"c_uniq"}, {"c_uniq"}}; !reflect.DeepEqual(keys(tbl), exp) {
+		t.Errorf("Expected keys %v, got %v", exp, keys(tbl))
+	}
+
+	ddl, _ = ParseDDL("db", "ALTER TABLE foo DROP INDEX a, ADD UNIQUE KEY bc USING BTREE (b, c), DROP KEY c_uniq")
+	tbl, err := tbl.alter(ddl.ColumnChanges)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := [][]string{{"a_2"}, {"bc"}, {"bc"}}; !reflect.DeepEqual(keys(tbl), exp) {
+		t.Errorf("Expected keys %v, got %v", exp, keys(tbl))
+	}
+}
+
+func str(s string) *string {
+	return &s
+}
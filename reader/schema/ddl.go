@@ -0,0 +1,367 @@
+package schema
+
+// DDL is a data definition statement that affects table schemas.
+type DDL struct {
+	Type DDLType
+	// Tables affected by the statement. Database name is set to the default
+	// one if the statement doesn't qualify table names.
+	Tables []TableName
+	// NewNames contains new table names for every renamed table in the same
+	// order as Tables.
+	NewNames []TableName
+	// Like is the table definition of which is copied by a CREATE TABLE ...
+	// LIKE statement.
+	Like *TableName
+	// IfNotExists is set for CREATE TABLE IF NOT EXISTS statements that
+	// leave an existing table intact.
+	IfNotExists bool
+	// Columns contains column definitions of a created table. It is nil if
+	// the definition is not known from the statement, e.g. for CREATE TABLE
+	// ... SELECT statements.
+	Columns []Column
+	// ColumnChanges contains column changes made by an ALTER TABLE statement.
+	ColumnChanges []ColumnChange
+}
+
+// DDLType is a type of a data definition statement.
+type DDLType int
+
+// TableName is a table name qualified with a database name.
+type TableName struct {
+	Database string
+	Table    string
+}
+
+// Data definition statement types.
+const (
+	// DDLCreateTable creates a new table.
+	DDLCreateTable DDLType = iota + 1
+	// DDLCreateTableLike creates a new table with a definition of another
+	// table.
+	DDLCreateTableLike
+	// DDLAlterTable changes table definition and could also rename it.
+	DDLAlterTable
+	// DDLDropTable drops one or more tables.
+	DDLDropTable
+	// DDLRenameTable renames one or more tables.
+	DDLRenameTable
+	// DDLTruncateTable removes all rows from a table, its definition is not
+	// changed.
+	DDLTruncateTable
+	// DDLDropDatabase drops all tables of a database, database name is the
+	// only entry of Tables.
+	DDLDropDatabase
+)
+
+func (t DDLType) String() string {
+	switch t {
+	case DDLCreateTable:
+		return "CREATE TABLE"
+	case DDLCreateTableLike:
+		return "CREATE TABLE LIKE"
+	case DDLAlterTable:
+		return "ALTER TABLE"
+	case DDLDropTable:
+		return "DROP TABLE"
+	case DDLRenameTable:
+		return "RENAME TABLE"
+	case DDLTruncateTable:
+		return "TRUNCATE TABLE"
+	case DDLDropDatabase:
+		return "DROP DATABASE"
+	default:
+		return "Unknown"
+	}
+}
+
+func (n TableName) String() string {
+	if n.Database == "" {
+		return n.Table
+	}
+	return n.Database + "." + n.Table
+}
+
+// ParseDDL parses a data definition statement that affects table schemas.
+// Unqualified table names are assumed to belong to the given database. False
+// is returned if the query is not such a statement, temporary tables are
+// ignored.
+func ParseDDL(database, query string) (*DDL, bool) {
+	p := &parser{tokens: lex(query), database: database}
+	var ddl *DDL
+	switch {
+	case p.keyword("CREATE"):
+		ddl = p.parseCreate()
+	case p.keyword("ALTER"):
+		ddl = p.parseAlter()
+	case p.keyword("DROP"):
+		ddl = p.parseDrop()
+	case p.keyword("RENAME"):
+		ddl = p.parseRename()
+	case p.keyword("TRUNCATE"):
+		ddl = p.parseTruncate()
+	}
+	return ddl, ddl != nil
+}
+
+//
+// Parser
+//
+
+type parser struct {
+	tokens   []token
+	pos      int
+	database string
+}
+
+// CREATE [OR REPLACE] TABLE [IF NOT EXISTS] name { LIKE name | (LIKE name) | ... }
+func (p *parser) parseCreate() *DDL {
+	p.keyword("OR", "REPLACE")
+	if !p.keyword("TABLE") {
+		// Temporary tables, databases, views, etc.
+		return nil
+	}
+	ifNotExists := p.keyword("IF", "NOT", "EXISTS")
+	name, ok := p.tableName()
+	if !ok {
+		return nil
+	}
+
+	ddl := &DDL{Type: DDLCreateTable, Tables: []TableName{name}, IfNotExists: ifNotExists}
+	paren := p.punct('(')
+	if p.keyword("LIKE") {
+		like, ok := p.tableName()
+		if !ok || paren && !p.punct(')') {
+			return nil
+		}
+		ddl.Type = DDLCreateTableLike
+		ddl.Like = &like
+		return ddl
+	}
+	if paren {
+		elems := p.tableElements()
+		// Columns of the selected rows are added to the table
+		if elems != nil && !p.containsKeyword("SELECT", "TABLE", "VALUES") {
+			ddl.Columns = columns(elems)
+		}
+	}
+	return ddl
+}
+
+// ALTER [ONLINE] [IGNORE] TABLE [IF EXISTS] name [spec [, spec] ...]
+func (p *parser) parseAlter() *DDL {
+	p.keyword("ONLINE")
+	p.keyword("IGNORE")
+	if !p.keyword("TABLE") {
+		return nil
+	}
+	p.keyword("IF", "EXISTS")
+	name, ok := p.tableName()
+	if !ok {
+		return nil
+	}
+
+	ddl := &DDL{Type: DDLAlterTable, Tables: []TableName{name}}
+	for _, spec := range p.alterSpecs() {
+		sp := &parser{tokens: spec, database: p.database}
+		if changes := sp.columnChanges(); changes != nil {
+			ddl.ColumnChanges = append(ddl.ColumnChanges, changes...)
+			continue
+		}
+		// RENAME [TO | AS] name, but not RENAME COLUMN, INDEX or KEY
+		sp.pos = 0
+		if !sp.keyword("RENAME") || sp.isKeyword("COLUMN", "INDEX", "KEY") {
+			continue
+		}
+		if !sp.keyword("TO") {
+			sp.keyword("AS")
+		}
+		if newName, ok := sp.tableName(); ok {
+			ddl.NewNames = []TableName{newName}
+		}
+	}
+	return ddl
+}
+
+// DROP TABLE [IF EXISTS] name [, name] ...
+// DROP {DATABASE | SCHEMA} [IF EXISTS] name
+func (p *parser) parseDrop() *DDL {
+	if p.keyword("DATABASE") || p.keyword("SCHEMA") {
+		p.keyword("IF", "EXISTS")
+		name, ok := p.ident()
+		if !ok {
+			return nil
+		}
+		return &DDL{Type: DDLDropDatabase, Tables: []TableName{{Database: name}}}
+	}
+	if !p.keyword("TABLE") && !p.keyword("TABLES") {
+		// Temporary tables, indexes, views, etc.
+		return nil
+	}
+	p.keyword("IF", "EXISTS")
+
+	ddl := &DDL{Type: DDLDropTable}
+	for {
+		name, ok := p.tableName()
+		if !ok {
+			return nil
+		}
+		ddl.Tables = append(ddl.Tables, name)
+		if !p.punct(',') {
+			return ddl
+		}
+	}
+}
+
+// RENAME TABLE name TO name [, name TO name] ...
+func (p *parser) parseRename() *DDL {
+	if !p.keyword("TABLE") && !p.keyword("TABLES") {
+		return nil
+	}
+
+	ddl := &DDL{Type: DDLRenameTable}
+	for {
+		from, ok := p.tableName()
+		if !ok || !p.keyword("TO") {
+			return nil
+		}
+		to, ok := p.tableName()
+		if !ok {
+			return nil
+		}
+		ddl.Tables = append(ddl.Tables, from)
+		ddl.NewNames = append(ddl.NewNames, to)
+		if !p.punct(',') {
+			return ddl
+		}
+	}
+}
+
+// TRUNCATE [TABLE] name
+func (p *parser) parseTruncate() *DDL {
+	p.keyword("TABLE")
+	name, ok := p.tableName()
+	if !ok {
+		return nil
+	}
+	return &DDL{Type: DDLTruncateTable, Tables: []TableName{name}}
+}
+
+// alterSpecs splits the rest of an ALTER TABLE statement into specifications
+// separated with commas. Commas inside parentheses are skipped.
+func (p *parser) alterSpecs() [][]token {
+	var specs [][]token
+	var depth, start int
+	start = p.pos
+	for ; p.pos < len(p.tokens); p.pos++ {
+		tok := p.tokens[p.pos]
+		if tok.typ != tokenPunct {
+			continue
+		}
+		switch tok.val {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",", ";":
+			if depth == 0 {
+				specs = append(specs, p.tokens[start:p.pos])
+				start = p.pos + 1
+			}
+		}
+	}
+	if start < len(p.tokens) {
+		specs = append(specs, p.tokens[start:])
+	}
+	return specs
+}
+
+// tableName reads a table name that could be qualified with a database name.
+func (p *parser) tableName() (TableName, bool) {
+	name, ok := p.ident()
+	if !ok {
+		return TableName{}, false
+	}
+	if !p.punct('.') {
+		return TableName{Database: p.database, Table: name}, true
+	}
+	table, ok := p.ident()
+	if !ok {
+		return TableName{}, false
+	}
+	return TableName{Database: name, Table: table}, true
+}
+
+// ident reads a quoted or an unquoted identifier.
+func (p *parser) ident() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	tok := p.tokens[p.pos]
+	if tok.typ != tokenWord && tok.typ != tokenQuoted {
+		return "", false
+	}
+	p.pos++
+	return tok.val, true
+}
+
+// keyword reads given sequence of keywords. Nothing is read unless the whole
+// sequence matches.
+func (p *parser) keyword(seq ...string) bool {
+	if p.pos+len(seq) > len(p.tokens) {
+		return false
+	}
+	for i, kw := range seq {
+		if !p.tokens[p.pos+i].is(kw) {
+			return false
+		}
+	}
+	p.pos += len(seq)
+	return true
+}
+
+// isKeyword returns true if the next token is one of given keywords. Nothing
+// is read.
+func (p *parser) isKeyword(kws ...string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	for _, kw := range kws {
+		if p.tokens[p.pos].is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsKeyword returns true if any of the remaining tokens outside of
+// parentheses is one of given keywords. Nothing is read.
+func (p *parser) containsKeyword(kws ...string) bool {
+	var depth int
+	for _, tok := range p.tokens[p.pos:] {
+		switch {
+		case tok.typ == tokenPunct && tok.val == "(":
+			depth++
+		case tok.typ == tokenPunct && tok.val == ")":
+			depth--
+		case depth == 0:
+			for _, kw := range kws {
+				if tok.is(kw) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// punct reads given punctuation character.
+func (p *parser) punct(c byte) bool {
+	if p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		if tok.typ == tokenPunct && tok.val[0] == c {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
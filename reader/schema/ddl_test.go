@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDDL(t *testing.T) {
+	tbl := func(db, name string) TableName { return TableName{Database: db, Table: name} }
+	inputs := []struct {
+		query string
+		ddl   *DDL
+	}{
+		{"alter\ttable foobar add column", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foobar")}}},
+		{"ALTER   TABLE foobar ADD COLUMN", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foobar")}}},
+		{"alter table    `foobar` add column", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foobar")}}},
+		{"ALTER TABLE `foobar` ADD COLUMN", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foobar")}}},
+		{"alter\ntable     \n\tfoobar\nadd column", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foobar")}}},
+		{"ALTER TABLE Foo_Bar111 ADD COLUMN", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "Foo_Bar111")}}},
+		{"SELECT * FROM foobar", nil},
+		{"SELECT * FROM `foobar`", nil},
+		{
+			"/* gh-ost */ ALTER TABLE `shop`.`foo-bar``s` ADD COLUMN x INT DEFAULT ',', RENAME TO shop.baz",
+			&DDL{
+				Type:          DDLAlterTable,
+				Tables:        []TableName{tbl("shop", "foo-bar`s")},
+				NewNames:      []TableName{tbl("shop", "baz")},
+				ColumnChanges: []ColumnChange{{Type: ColumnAdd, Column: Column{Name: "x", Type: "int", Nullable: true, Default: str(",")}}},
+			},
+		},
+		{
+			"ALTER TABLE foo RENAME COLUMN a TO b",
+			&DDL{
+				Type:          DDLAlterTable,
+				Tables:        []TableName{tbl("db", "foo")},
+				ColumnChanges: []ColumnChange{{Type: ColumnRename, Name: "a", Column: Column{Name: "b"}}},
+			},
+		},
+		{"ALTER TABLE foo RENAME INDEX a TO b", &DDL{Type: DDLAlterTable, Tables: []TableName{tbl("db", "foo")}}},
+		{"CREATE TABLE IF NOT EXISTS foo (id INT)", &DDL{Type: DDLCreateTable, Tables: []TableName{tbl("db", "foo")}, IfNotExists: true, Columns: []Column{{Name: "id", Type: "int", Nullable: true}}}},
+		{"CREATE TABLE IF NOT EXISTS foo LIKE bar", &DDL{Type: DDLCreateTableLike, Tables: []TableName{tbl("db", "foo")}, Like: &TableName{"db", "bar"}, IfNotExists: true}},
+		{
+			"create table other.foo(id int) /*!50100 PARTITION BY HASH (id) */",
+			&DDL{Type: DDLCreateTable, Tables: []TableName{tbl("other", "foo")}, Columns: []Column{{Name: "id", Type: "int", Nullable: true}}},
+		},
+		{"CREATE TABLE foo LIKE other.bar", &DDL{Type: DDLCreateTableLike, Tables: []TableName{tbl("db", "foo")}, Like: &TableName{"other", "bar"}}},
+		{"CREATE TABLE foo (LIKE bar)", &DDL{Type: DDLCreateTableLike, Tables: []TableName{tbl("db", "foo")}, Like: &TableName{"db", "bar"}}},
+		{"CREATE TEMPORARY TABLE foo (id INT)", nil},
+		{"CREATE DATABASE foo", nil},
+		{"CREATE VIEW foo AS SELECT 1", nil},
+		{
+			"DROP TABLE IF EXISTS `foo`, other.bar /* generated by server */",
+			&DDL{Type: DDLDropTable, Tables: []TableName{tbl("db", "foo"), tbl("other", "bar")}},
+		},
+		{"DROP TEMPORARY TABLE foo", nil},
+		{"DROP INDEX idx ON foo", nil},
+		{"DROP DATABASE IF EXISTS `other`", &DDL{Type: DDLDropDatabase, Tables: []TableName{{Database: "other"}}}},
+		{
+			"RENAME TABLE foo TO _foo_del, `_foo_gho` TO foo",
+			&DDL{
+				Type:     DDLRenameTable,
+				Tables:   []TableName{tbl("db", "foo"), tbl("db", "_foo_gho")},
+				NewNames: []TableName{tbl("db", "_foo_del"), tbl("db", "foo")},
+			},
+		},
+		{"RENAME USER foo TO bar", nil},
+		{"TRUNCATE foo", &DDL{Type: DDLTruncateTable, Tables: []TableName{tbl("db", "foo")}}},
+		{"-- comment\nTRUNCATE TABLE # comment\n foo", &DDL{Type: DDLTruncateTable, Tables: []TableName{tbl("db", "foo")}}},
+	}
+
+	for _, in := range inputs {
+		ddl, ok := ParseDDL("db", in.query)
+		if ok != (in.ddl != nil) {
+			t.Errorf("Expected match to be %v in query %q", in.ddl != nil, in.query)
+			continue
+		}
+		if !reflect.DeepEqual(ddl, in.ddl) {
+			t.Errorf("Expected %+v, got %+v in query %q", in.ddl, ddl, in.query)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/localhots/bocadillo/binlog"
+)
+
+// History is a log of table definition changes ordered by their positions in
+// the binary log. It allows to restore table definitions as they were at any
+// position.
+type History struct {
+	Entries []HistoryEntry
+	path    string
+}
+
+// HistoryEntry is a table definition that was set by a statement at the given
+// position.
+type HistoryEntry struct {
+	// Position right after the statement that changed the table.
+	Position binlog.Position
+	// GTID of the transaction that changed the table, if known.
+	GTID  string
+	Table TableName
+	// Columns is nil if the table was dropped.
+	Columns []Column
+}
+
+// NewHistory creates a new empty history that is kept in memory.
+func NewHistory() *History {
+	return &History{}
+}
+
+// OpenHistory loads history from a file. Every new entry is appended to the
+// same file as a line of JSON. Empty history is returned if the file doesn't
+// exist. A partially written last line that is left after a crash is
+// discarded.
+func OpenHistory(path string) (*History, error) {
+	h := &History{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		h.path = path
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n := bytes.LastIndexByte(data, '\n') + 1
+	for _, line := range bytes.Split(data[:n], []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		h.Record(e)
+	}
+	if n < len(data) {
+		if err := os.Truncate(path, int64(n)); err != nil {
+			return nil, err
+		}
+	}
+	h.path = path
+	return h, nil
+}
+
+// Record adds a new entry to the history. Entry that was recorded for the same
+// table at the same position before is replaced, so that replaying the binary
+// log again doesn't produce duplicates.
+func (h *History) Record(e HistoryEntry) error {
+	i := sort.Search(len(h.Entries), func(i int) bool {
+		return h.Entries[i].Position.Compare(e.Position) > 0
+	})
+	for j := i - 1; j >= 0 && h.Entries[j].Position == e.Position; j-- {
+		if h.Entries[j].Table == e.Table {
+			h.Entries[j] = e
+			return h.save(e)
+		}
+	}
+
+	h.Entries = append(h.Entries, HistoryEntry{})
+	copy(h.Entries[i+1:], h.Entries[i:])
+	h.Entries[i] = e
+	return h.save(e)
+}
+
+// Schema returns table definitions as they were at the given position.
+func (h *History) Schema(pos binlog.Position) *Schema {
+	s := NewSchema()
+	for _, e := range h.Entries {
+		if e.Position.Compare(pos) > 0 {
+			break
+		}
+		if e.Columns == nil {
+			s.Delete(e.Table.Database, e.Table.Table)
+		} else {
+			s.Update(e.Table.Database, e.Table.Table, e.Columns)
+		}
+	}
+	return s
+}
+
+// SchemaForGTIDs returns table definitions as they were once the given set of
+// transactions was executed. Entries without a MySQL GTID are skipped.
+func (h *History) SchemaForGTIDs(gtids binlog.GTIDSet) *Schema {
+	s := NewSchema()
+	for _, e := range h.Entries {
+		set, err := binlog.ParseGTIDSet(e.GTID)
+		if err != nil || set.IsEmpty() || !gtids.ContainsSet(set) {
+			continue
+		}
+		if e.Columns == nil {
+			s.Delete(e.Table.Database, e.Table.Table)
+		} else {
+			s.Update(e.Table.Database, e.Table.Table, e.Columns)
+		}
+	}
+	return s
+}
+
+// save appends an entry to the history file if there is one. Replaced
+// entries are appended too, the last one wins when the file is loaded.
+func (h *History) save(e HistoryEntry) error {
+	if h.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(h.path)
+	created := os.IsNotExist(err)
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// Directory entry of a new file must be durable as well
+	if created {
+		return syncDir(filepath.Dir(h.path))
+	}
+	return nil
+}
+
+// syncDir flushes directory entries, making a new file durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
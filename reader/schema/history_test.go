@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+)
+
+const testSID = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+func TestHistory(t *testing.T) {
+	foo := TableName{Database: "db", Table: "foo"}
+	v1 := []Column{{Name: "id"}}
+	v2 := []Column{{Name: "id"}, {Name: "name"}}
+
+	h := NewHistory()
+	entries := []HistoryEntry{
+		{Position: binlog.Position{File: "mysql-bin.000002", Offset: 100}, Table: foo, Columns: v2},
+		{Position: binlog.Position{File: "mysql-bin.000001", Offset: 500}, Table: foo, Columns: v1},
+		{Position: binlog.Position{File: "mysql-bin.000002", Offset: 300}, Table: foo},
+		// Sequence number grows past six digits
+		{Position: binlog.Position{File: "mysql-bin.1000000", Offset: 100}, Table: foo, Columns: v1},
+		// Replayed statement replaces the existing entry
+		{Position: binlog.Position{File: "mysql-bin.000002", Offset: 100}, Table: foo, Columns: v2},
+	}
+	for _, e := range entries {
+		if err := h.Record(e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(h.Entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(h.Entries))
+	}
+
+	tests := []struct {
+		pos binlog.Position
+		exp []Column
+	}{
+		{binlog.Position{File: "mysql-bin.000001", Offset: 4}, nil},
+		{binlog.Position{File: "mysql-bin.000001", Offset: 500}, v1},
+		{binlog.Position{File: "mysql-bin.000002", Offset: 4}, v1},
+		{binlog.Position{File: "mysql-bin.000002", Offset: 200}, v2},
+		{binlog.Position{File: "mysql-bin.000003", Offset: 4}, nil},
+		{binlog.Position{File: "mysql-bin.999999", Offset: 4}, nil},
+		{binlog.Position{File: "mysql-bin.1000000", Offset: 200}, v1},
+	}
+	for _, test := range tests {
+		tbl := h.Schema(test.pos).Table("db", "foo")
+		if test.exp == nil {
+			if tbl != nil {
+				t.Errorf("Expected no table at %v, got %+v", test.pos, tbl)
+			}
+			continue
+		}
+		if tbl == nil || !reflect.DeepEqual(tbl.columns, test.exp) {
+			t.Errorf("Expected columns %+v at %v, got %+v", test.exp, test.pos, tbl)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bocadillo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history.json")
+	h, err := OpenHistory(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	foo := TableName{Database: "db", Table: "foo"}
+	e1 := HistoryEntry{
+		Position: binlog.Position{File: "mysql-bin.000001", Offset: 500},
+		GTID:     testSID + ":1",
+		Table:    foo,
+		Columns:  []Column{{Name: "id"}},
+	}
+	e2 := HistoryEntry{
+		Position: binlog.Position{File: "mysql-bin.000001", Offset: 500},
+		GTID:     testSID + ":1",
+		Table:    foo,
+		Columns:  []Column{{Name: "id", Unsigned: true}},
+	}
+	for _, e := range []HistoryEntry{e1, e2} {
+		if err := h.Record(e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Partially written entry is discarded
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.WriteString(`{"Position":`)
+	f.Close()
+
+	h, err = OpenHistory(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(h.Entries) != 1 || !reflect.DeepEqual(h.Entries[0], e2) {
+		t.Errorf("Expected entries %+v, got %+v", []HistoryEntry{e2}, h.Entries)
+	}
+
+	e3 := HistoryEntry{
+		Position: binlog.Position{File: "mysql-bin.000002", Offset: 100},
+		Table:    foo,
+	}
+	if err := h.Record(e3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	h, err = OpenHistory(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := []HistoryEntry{e2, e3}; !reflect.DeepEqual(h.Entries, exp) {
+		t.Errorf("Expected entries %+v, got %+v", exp, h.Entries)
+	}
+}
+
+func TestHistorySchemaForGTIDs(t *testing.T) {
+	h := NewHistory()
+	for i, gtid := range []string{testSID + ":1", testSID + ":2"} {
+		h.Record(HistoryEntry{
+			Position: binlog.Position{File: "mysql-bin.000001", Offset: uint64(100 * (i + 1))},
+			GTID:     gtid,
+			Table:    TableName{Database: "db", Table: "foo"},
+			Columns:  make([]Column, i+1),
+		})
+	}
+
+	gtids, err := binlog.ParseGTIDSet(testSID + ":1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tbl := h.SchemaForGTIDs(gtids).Table("db", "foo")
+	if tbl == nil || len(tbl.columns) != 1 {
+		t.Errorf("Expected table with 1 column, got %+v", tbl)
+	}
+}
+
+func TestManagerHistory(t *testing.T) {
+	h := NewHistory()
+	start := binlog.Position{File: "mysql-bin.000001", Offset: 4}
+
+	m := NewManager(nil)
+	m.UseHistory(h, start, binlog.GTIDSet{})
+	m.managed[TableName{Database: "db", Table: "foo"}] = struct{}{}
+	queries := []string{
+		"CREATE TABLE foo (id INT UNSIGNED)",
+		"ALTER TABLE foo ADD COLUMN name TEXT",
+	}
+	for i, q := range queries {
+		pos := binlog.Position{File: "mysql-bin.000001", Offset: uint64(100 * (i + 1))}
+		if err := m.ProcessQueryAt(pos, "", "db", q); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(h.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(h.Entries))
+	}
+
+	// Table definition is restored from history without querying the database
+	m = NewManager(nil)
+	m.UseHistory(h, binlog.Position{File: "mysql-bin.000001", Offset: 150}, binlog.GTIDSet{})
+	if err := m.Manage("db", "foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tbl := m.Schema.Table("db", "foo")
+	exp := []Column{{Name: "id", Type: "int", Unsigned: true, Nullable: true}}
+	if tbl == nil || !reflect.DeepEqual(tbl.columns, exp) {
+		t.Errorf("Expected columns %+v, got %+v", exp, tbl)
+	}
+}
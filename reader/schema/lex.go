@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"strings"
+)
+
+type token struct {
+	typ tokenType
+	val string
+}
+
+type tokenType int
+
+const (
+	// Unquoted identifier, keyword or number
+	tokenWord tokenType = iota
+	// Identifier quoted with backticks
+	tokenQuoted
+	// String literal
+	tokenString
+	// Any other character
+	tokenPunct
+)
+
+// lex splits an SQL statement into tokens. Comments are skipped, except for
+// the executable ones (/*! ... */) the contents of which are part of the
+// statement.
+func lex(query string) []token {
+	var tokens []token
+	var inComment bool
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '#' || strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || isSpace(query[i+2])):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*!") || strings.HasPrefix(query[i:], "/*M!"):
+			// Executable comment with an optional version number
+			i += strings.IndexByte(query[i:], '!') + 1
+			for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+			inComment = true
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += 2 + end + 2
+		case inComment && strings.HasPrefix(query[i:], "*/"):
+			i += 2
+			inComment = false
+		case c == '`':
+			val, n := readQuoted(query[i:], '`')
+			tokens = append(tokens, token{typ: tokenQuoted, val: val})
+			i += n
+		case c == '\'' || c == '"':
+			val, n := readQuoted(query[i:], c)
+			tokens = append(tokens, token{typ: tokenString, val: val})
+			i += n
+		case isWordChar(c):
+			j := i
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			tokens = append(tokens, token{typ: tokenWord, val: query[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, token{typ: tokenPunct, val: query[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// readQuoted reads a quoted identifier or a string literal. Quote character is
+// escaped by doubling it, strings also support backslash escapes. Unquoted
+// value is returned along with the number of bytes read.
+func readQuoted(str string, quote byte) (string, int) {
+	var val strings.Builder
+	for i := 1; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case c == '\\' && quote != '`' && i+1 < len(str):
+			i++
+			val.WriteByte(str[i])
+		case c == quote && i+1 < len(str) && str[i+1] == quote:
+			i++
+			val.WriteByte(quote)
+		case c == quote:
+			return val.String(), i + 1
+		default:
+			val.WriteByte(c)
+		}
+	}
+	return val.String(), len(str)
+}
+
+func (t token) is(keyword string) bool {
+	return t.typ == tokenWord && strings.EqualFold(t.val, keyword)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// isWordChar returns true for characters permitted in unquoted identifiers.
+// Multibyte characters are allowed as well.
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c >= 0x80
+}
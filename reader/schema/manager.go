@@ -2,48 +2,269 @@ package schema
 
 import (
 	"database/sql"
-	"regexp"
+	"fmt"
 	"strings"
+
+	"github.com/localhots/bocadillo/binlog"
 )
 
 // Manager maintains table schemas.
 type Manager struct {
 	Schema *Schema
 	db     *sql.DB
+
+	// Managed tables are tracked by name, a table that is dropped and then
+	// created again remains managed. Tables that match are managed once
+	// they are affected by a DDL statement
+	managed map[TableName]struct{}
+	match   func(database, table string) bool
+
+	// Changes are recorded into history if it is enabled. Definitions of
+	// managed tables are restored from it instead of being queried
+	history  *History
+	restored *Schema
+	pos      binlog.Position
+	gtid     string
 }
 
 // NewManager creates a new schema manager.
 func NewManager(db *sql.DB) *Manager {
 	return &Manager{
-		Schema: NewSchema(),
-		db:     db,
+		Schema:  NewSchema(),
+		db:      db,
+		managed: make(map[TableName]struct{}),
+	}
+}
+
+// UseHistory makes the manager record table definition changes into the given
+// history. Definitions of tables that are managed afterwards are restored as
+// they were at the given position. Executed GTID set is used instead if the
+// position is unknown.
+func (m *Manager) UseHistory(h *History, pos binlog.Position, gtids binlog.GTIDSet) {
+	m.history = h
+	m.pos = pos
+	if pos.File != "" {
+		m.restored = h.Schema(pos)
+	} else {
+		m.restored = h.SchemaForGTIDs(gtids)
 	}
 }
 
 // Manage adds given tables to a list of managed tables and updates its details.
 func (m *Manager) Manage(database, table string) error {
-	cols, err := m.tableColumns(database, table)
-	if err != nil {
-		return err
+	name := TableName{Database: database, Table: table}
+	m.managed[name] = struct{}{}
+	if m.restored != nil {
+		if tbl := m.restored.Table(database, table); tbl != nil {
+			m.Schema.Update(database, table, tbl.columns)
+			return nil
+		}
 	}
+	return m.refresh(name)
+}
 
-	m.Schema.Update(database, table, cols)
-	return nil
+// ManageAt is like Manage but it also sets the position of the event that
+// referenced the table in the binary log, table definition that is queried is
+// recorded into history at this position.
+func (m *Manager) ManageAt(pos binlog.Position, gtid, database, table string) error {
+	m.pos, m.gtid = pos, gtid
+	return m.Manage(database, table)
+}
+
+// ManageMatching makes the manager manage tables for which the given function
+// returns true once they are created, altered or renamed. This way their
+// definitions are evolved offline, same as for the tables that are already
+// managed.
+func (m *Manager) ManageMatching(match func(database, table string) bool) {
+	m.match = match
 }
 
-// ProcessQuery accepts an SQL query and updates schema if required.
+// ProcessQuery accepts an SQL query and updates schema if required. Column
+// changes are applied to known table definitions offline, table definition is
+// queried from the database otherwise.
 func (m *Manager) ProcessQuery(database, query string) error {
-	if tableName, ok := changedTable(query); ok {
-		if tbl := m.Schema.Table(database, tableName); tbl != nil {
-			return m.Manage(database, tableName)
+	ddl, ok := ParseDDL(database, query)
+	if !ok {
+		return nil
+	}
+	if ddl.Type != DDLDropDatabase {
+		for _, name := range append(ddl.Tables, ddl.NewNames...) {
+			m.manageMatching(name)
+		}
+	}
+
+	switch ddl.Type {
+	case DDLCreateTable:
+		name := ddl.Tables[0]
+		if !m.isManaged(name) || ddl.IfNotExists && m.exists(name) {
+			break
+		}
+		if ddl.Columns == nil {
+			return m.refresh(name)
+		}
+		return m.update(name, ddl.Columns)
+	case DDLCreateTableLike:
+		name := ddl.Tables[0]
+		if m.isManaged(name) && !(ddl.IfNotExists && m.exists(name)) {
+			return m.copyTable(*ddl.Like, name)
+		}
+	case DDLAlterTable:
+		name := ddl.Tables[0]
+		altered, err := m.alter(name, ddl.ColumnChanges)
+		if err != nil {
+			return err
+		}
+		if len(ddl.NewNames) > 0 {
+			if err := m.rename(name, ddl.NewNames[0]); err != nil {
+				return err
+			}
+			name = ddl.NewNames[0]
+		}
+		if m.isManaged(name) && !altered {
+			return m.refresh(name)
+		}
+	case DDLDropTable:
+		for _, name := range ddl.Tables {
+			if err := m.delete(name); err != nil {
+				return err
+			}
+		}
+	case DDLRenameTable:
+		for i, from := range ddl.Tables {
+			if err := m.rename(from, ddl.NewNames[i]); err != nil {
+				return err
+			}
+		}
+	case DDLDropDatabase:
+		for name := range m.managed {
+			if name.Database != ddl.Tables[0].Database {
+				continue
+			}
+			if err := m.delete(name); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// ProcessQueryAt is like ProcessQuery but it also sets the position of the
+// statement in the binary log, changes are recorded into history at this
+// position. Position should point right after the statement.
+func (m *Manager) ProcessQueryAt(pos binlog.Position, gtid, database, query string) error {
+	m.pos, m.gtid = pos, gtid
+	return m.ProcessQuery(database, query)
+}
+
+// alter applies column changes to the table definition. Table schema is
+// evolved offline so that it matches the position in the binary log rather
+// than the current state of the database. False is returned if the table is
+// not tracked. Changes that can't be applied are reported, the current state
+// of the database could already differ from the binary log.
+func (m *Manager) alter(name TableName, changes []ColumnChange) (bool, error) {
+	tbl := m.Schema.Table(name.Database, name.Table)
+	if tbl == nil {
+		return false, nil
+	}
+	altered, err := tbl.alter(changes)
+	if err != nil {
+		return false, fmt.Errorf("alter table %s.%s: %w", name.Database, name.Table, err)
+	}
+	return true, m.update(name, altered.columns)
+}
+
+// rename moves table definition to a new name. Definition is dropped if the
+// new name is not managed.
+func (m *Manager) rename(from, to TableName) error {
+	if m.isManaged(to) {
+		if err := m.copyTable(from, to); err != nil {
+			return err
+		}
+	}
+	return m.delete(from)
+}
+
+// copyTable copies table definition to a new name. Definition of the new
+// table is queried if the source table is not tracked.
+func (m *Manager) copyTable(from, to TableName) error {
+	if tbl := m.Schema.Table(from.Database, from.Table); tbl != nil {
+		return m.update(to, append([]Column{}, tbl.columns...))
+	}
+	return m.refresh(to)
+}
+
+// refresh queries table definition.
+func (m *Manager) refresh(name TableName) error {
+	cols, err := m.tableColumns(name.Database, name.Table)
+	if err != nil {
+		return err
+	}
+	return m.update(name, cols)
+}
+
+// update sets table definition and records the change.
+func (m *Manager) update(name TableName, cols []Column) error {
+	m.Schema.Update(name.Database, name.Table, cols)
+	return m.record(name, cols)
+}
+
+// delete removes table definition and records the change.
+func (m *Manager) delete(name TableName) error {
+	if m.Schema.Table(name.Database, name.Table) == nil {
+		return nil
+	}
+	m.Schema.Delete(name.Database, name.Table)
+	return m.record(name, nil)
+}
+
+func (m *Manager) record(name TableName, cols []Column) error {
+	if m.history == nil {
+		return nil
+	}
+	return m.history.Record(HistoryEntry{
+		Position: m.pos,
+		GTID:     m.gtid,
+		Table:    name,
+		Columns:  cols,
+	})
+}
+
+// IsManaged returns true if the table is managed.
+func (m *Manager) IsManaged(database, table string) bool {
+	return m.isManaged(TableName{Database: database, Table: table})
+}
+
+func (m *Manager) isManaged(name TableName) bool {
+	_, ok := m.managed[name]
+	return ok
+}
+
+// exists returns true if the table definition is known.
+func (m *Manager) exists(name TableName) bool {
+	return m.Schema.Table(name.Database, name.Table) != nil
+}
+
+// manageMatching starts managing a table if it matches. Table definition is
+// restored from history if it is there.
+func (m *Manager) manageMatching(name TableName) {
+	if m.match == nil || m.isManaged(name) || !m.match(name.Database, name.Table) {
+		return
+	}
+	m.managed[name] = struct{}{}
+	if m.restored != nil {
+		if tbl := m.restored.Table(name.Database, name.Table); tbl != nil {
+			m.Schema.Update(name.Database, name.Table, tbl.columns)
+		}
+	}
+}
+
+// tableColumns queries column definitions along with their primary and unique
+// key membership.
 func (m *Manager) tableColumns(database, table string) ([]Column, error) {
 	rows, err := m.db.Query(`
-		SELECT COLUMN_NAME, COLUMN_TYPE 
+		SELECT
+			COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT,
+			CHARACTER_MAXIMUM_LENGTH, CHARACTER_SET_NAME, COLLATION_NAME, EXTRA
 		FROM INFORMATION_SCHEMA.COLUMNS 
 		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? 
 		ORDER BY ORDINAL_POSITION ASC
@@ -55,26 +276,65 @@ func (m *Manager) tableColumns(database, table string) ([]Column, error) {
 
 	cols := make([]Column, 0)
 	for rows.Next() {
-		var col Column
-		var typ string
-		err := rows.Scan(&col.Name, &typ)
+		var name, typ, nullable, extra string
+		var def, charset, collation sql.NullString
+		var length sql.NullInt64
+		err := rows.Scan(&name, &typ, &nullable, &def, &length, &charset, &collation, &extra)
 		if err != nil {
 			return nil, err
 		}
-		if strings.Contains(strings.ToLower(typ), "unsigned") {
-			col.Unsigned = true
+
+		col := parseColumnType(typ)
+		col.Name = name
+		col.Nullable = nullable == "YES"
+		if def.Valid {
+			col.Default = &def.String
+		}
+		if length.Valid {
+			col.Length = uint64(length.Int64)
 		}
+		col.Charset, col.Collation = charset.String, collation.String
+		// Columns with expression defaults are marked as DEFAULT_GENERATED by
+		// MySQL, those are not generated columns
+		extra = strings.ToUpper(extra)
+		col.Generated = strings.Contains(extra, "VIRTUAL") ||
+			strings.Contains(extra, "STORED") || strings.Contains(extra, "PERSISTENT")
 		cols = append(cols, col)
 	}
-	return cols, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cols, m.tableKeys(database, table, cols)
 }
 
-var alterRegexp = regexp.MustCompile(`(?im)^alter[\s\t\n]+table[\s\t\n]+` + "`" + `?([a-z0-9_]+)`)
+// tableKeys marks columns that are parts of primary and unique keys.
+func (m *Manager) tableKeys(database, table string, cols []Column) error {
+	rows, err := m.db.Query(`
+		SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME
+		FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, database, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-func changedTable(query string) (string, bool) {
-	m := alterRegexp.FindAllStringSubmatch(query, -1)
-	if len(m) > 0 {
-		return m[0][1], true
+	for rows.Next() {
+		var index string
+		var nonUnique int
+		// Column name is NULL for functional key parts
+		var name sql.NullString
+		if err := rows.Scan(&index, &nonUnique, &name); err != nil {
+			return err
+		}
+		i := columnIndex(cols, name.String)
+		switch {
+		case i < 0, nonUnique != 0:
+		case index == "PRIMARY":
+			cols[i].PrimaryKey = true
+		default:
+			cols[i].addUniqueKey(index)
+		}
 	}
-	return "", false
+	return rows.Err()
 }
@@ -1,34 +1,110 @@
 package schema
 
-import "testing"
-
-func TestChangedTable(t *testing.T) {
-	inputs := []struct {
-		query, tbl string
-		ok         bool
-	}{
-		{"alter\ttable foobar add column", "foobar", true},
-		{"ALTER   TABLE foobar ADD COLUMN", "foobar", true},
-		{"alter table    `foobar` add column", "foobar", true},
-		{"ALTER TABLE `foobar` ADD COLUMN", "foobar", true},
-		{"alter\ntable     \n\tfoobar\nadd column", "foobar", true},
-		{"ALTER TABLE Foo_Bar111 ADD COLUMN", "Foo_Bar111", true},
-		{"SELECT * FROM foobar", "", false},
-		{"SELECT * FROM `foobar`", "", false},
-	}
-
-	for _, in := range inputs {
-		out, ok := changedTable(in.query)
-		if ok != in.ok {
-			if ok {
-				t.Errorf("Didn't expect to match table %q in query %q", out, in.query)
-			} else {
-				t.Errorf("Expected to match table %q in query %q", in.tbl, in.query)
-			}
-			continue
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestManagerProcessQuery(t *testing.T) {
+	m := NewManager(nil)
+	cols := []Column{{Name: "id", Unsigned: true}}
+	for _, name := range []string{"foo", "bar"} {
+		m.managed[TableName{Database: "db", Table: name}] = struct{}{}
+		m.Schema.Update("db", name, cols)
+	}
+
+	if err := m.ProcessQuery("db", "RENAME TABLE foo TO _foo_old, bar TO foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.Schema.Table("db", "bar") != nil || m.Schema.Table("db", "_foo_old") != nil {
+		t.Errorf("Expected renamed tables to be removed")
+	}
+	if tbl := m.Schema.Table("db", "foo"); tbl == nil || tbl.Column(0).Name != "id" {
+		t.Errorf("Expected foo to be renamed from bar")
+	}
+
+	if err := m.ProcessQuery("other", "DROP TABLE db.foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.Schema.Table("db", "foo") != nil {
+		t.Errorf("Expected foo to be dropped")
+	}
+	if err := m.ProcessQuery("db", "TRUNCATE TABLE foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestManagerCreateIfNotExists(t *testing.T) {
+	m := NewManager(nil)
+	cols := []Column{{Name: "id", Unsigned: true}}
+	for _, name := range []string{"foo", "bar"} {
+		m.managed[TableName{Database: "db", Table: name}] = struct{}{}
+	}
+	m.Schema.Update("db", "foo", cols)
+
+	// Existing table is left intact
+	queries := []string{
+		"CREATE TABLE IF NOT EXISTS foo (name TEXT)",
+		"CREATE TABLE IF NOT EXISTS foo LIKE baz",
+	}
+	for _, q := range queries {
+		if err := m.ProcessQuery("db", q); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
 		}
-		if out != in.tbl {
-			t.Errorf("Expected to match table %q, got %q in query %q", in.tbl, out, in.query)
+		if tbl := m.Schema.Table("db", "foo"); tbl == nil || !reflect.DeepEqual(tbl.columns, cols) {
+			t.Errorf("Expected foo to be left intact by %q, got %+v", q, tbl)
 		}
 	}
+
+	// Missing table is created
+	if err := m.ProcessQuery("db", "CREATE TABLE IF NOT EXISTS bar LIKE foo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tbl := m.Schema.Table("db", "bar"); tbl == nil || !reflect.DeepEqual(tbl.columns, cols) {
+		t.Errorf("Expected bar to be created, got %+v", tbl)
+	}
+}
+
+func TestManagerOfflineAlter(t *testing.T) {
+	// Database is not available, all changes are applied offline
+	m := NewManager(nil)
+	m.managed[TableName{Database: "db", Table: "foo"}] = struct{}{}
+	if err := m.ProcessQuery("db", "CREATE TABLE foo (id INT UNSIGNED, name TEXT)"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	queries := []string{
+		"ALTER TABLE foo ADD COLUMN age TINYINT UNSIGNED AFTER id",
+		"ALTER TABLE foo MODIFY id BIGINT, CHANGE name full_name VARCHAR(255) FIRST",
+		"ALTER TABLE foo ADD INDEX idx (age)",
+	}
+	for _, q := range queries {
+		if err := m.ProcessQuery("db", q); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tbl := m.Schema.Table("db", "foo")
+	exp := []Column{
+		{Name: "full_name", Type: "varchar", Length: 255, Nullable: true},
+		{Name: "id", Type: "bigint", Nullable: true},
+		{Name: "age", Type: "tinyint", Unsigned: true, Nullable: true},
+	}
+	if tbl == nil || !reflect.DeepEqual(tbl.columns, exp) {
+		t.Errorf("Expected columns %+v, got %+v", exp, tbl)
+	}
+}
+
+func TestManagerAlterError(t *testing.T) {
+	m := NewManager(nil)
+	m.managed[TableName{Database: "db", Table: "foo"}] = struct{}{}
+	// Definition recorded without key names
+	m.Schema.Update("db", "foo", []Column{{Name: "id", UniqueKey: true}})
+
+	// Change that can't be applied is reported instead of querying the
+	// current definition
+	err := m.ProcessQuery("db", "ALTER TABLE foo DROP INDEX idx")
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected error %v, got %v", ErrUnknownKey, err)
+	}
 }
@@ -10,13 +10,45 @@ type Table struct {
 	columns []Column
 }
 
-// Column carries two key column parameters that are not available in the binary
-// log of older versions of MySQL.
+// Column carries column parameters, some of which are not available in the
+// binary log of older versions of MySQL.
 type Column struct {
 	Name string
+	// Type is a lower case name of the data type, e.g. "int" or "varchar".
+	Type string
+	// Length is the maximum length of string types, the number of bits of
+	// BIT type or the display width of integer types. It is zero if not
+	// specified.
+	Length uint64
+	// Precision is the number of significant digits of decimal and floating
+	// point types or the fractional seconds precision of temporal types.
+	// Scale is the number of digits after the decimal point.
+	Precision int
+	Scale     int
 	// Unsigned is true if the column is of integer or decimal types and is
 	// unsigned.
 	Unsigned bool
+	Nullable bool
+	// Default is the default value or expression as it is reported by the
+	// server, string literals are unquoted. It is nil if there is no default
+	// value or it is NULL.
+	Default *string
+	// Charset and Collation are only set for string types. Offline
+	// definitions only carry explicitly specified values.
+	Charset   string
+	Collation string
+	// Values is a list of ENUM or SET members.
+	Values []string
+	// Generated is true for virtual and stored generated columns.
+	Generated bool
+	// PrimaryKey is true if the column is a part of the primary key.
+	// UniqueKey is true if the column is a part of any unique key.
+	PrimaryKey bool
+	UniqueKey  bool
+	// UniqueKeys is a list of names of the unique keys the column is a part
+	// of. Names are missing from definitions recorded before they were
+	// tracked.
+	UniqueKeys []string
 }
 
 // NewSchema creates a new managed schema object.
@@ -43,6 +75,21 @@ func (s Schema) Update(database, table string, cols []Column) {
 	s.tables[database][table] = Table{columns: cols}
 }
 
+// Delete removes table definition for a given database and table name pair.
+func (s Schema) Delete(database, table string) {
+	if d, ok := s.tables[database]; ok {
+		delete(d, table)
+		if len(d) == 0 {
+			delete(s.tables, database)
+		}
+	}
+}
+
+// DeleteDatabase removes definitions of all tables of a given database.
+func (s Schema) DeleteDatabase(database string) {
+	delete(s.tables, database)
+}
+
 // Column returns column details for the given column index. If index is out of
 // range nil is returned.
 func (t Table) Column(i int) *Column {
@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+// testSemiSyncSource requests acknowledgement of XID events.
+type testSemiSyncSource struct {
+	Source
+	ack  bool
+	acks []binlog.Position
+}
+
+func (s *testSemiSyncSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	data, err := s.Source.ReadPacket(ctx)
+	s.ack = err == nil && binlog.EventType(data[4]) == binlog.EventTypeXID
+	return data, err
+}
+
+func (s *testSemiSyncSource) AckRequested() bool {
+	return s.ack
+}
+
+func (s *testSemiSyncSource) SemiSyncAck(pos binlog.Position) error {
+	s.acks = append(s.acks, pos)
+	return nil
+}
+
+func TestSemiSyncAck(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(100),
+	}
+	bsrc, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	src := &testSemiSyncSource{Source: bsrc}
+	r := NewFromSource(src)
+
+	for _, evt := range events {
+		e, err := r.ReadEvent(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		exp := binlog.EventType(evt[4]) == binlog.EventTypeXID
+		if e.AckRequested != exp {
+			t.Errorf("Expected %s event ack requested to be %v", e.Header.Type, exp)
+		}
+		if e.AckRequested {
+			if err := r.Ack(); err != nil {
+				t.Fatalf("Failed to ack: %v", err)
+			}
+		}
+	}
+	if exp := uint64(testFileSize(events...)); len(src.acks) != 1 || src.acks[0].Offset != exp {
+		t.Errorf("Expected a single ack at %d, got %v", exp, src.acks)
+	}
+
+	r = NewFromSource(bsrc)
+	if err := r.Ack(); err != driver.ErrSemiSyncUnsupported {
+		t.Errorf("Expected unsupported error, got %v", err)
+	}
+}
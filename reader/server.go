@@ -0,0 +1,229 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+// BinaryLog describes a binary log file present on master.
+type BinaryLog struct {
+	File string
+	Size uint64
+}
+
+// MasterStatus describes current position of master in its binary log.
+type MasterStatus struct {
+	binlog.Position
+	// ExecutedGTIDs is empty unless GTID mode is enabled.
+	ExecutedGTIDs binlog.GTIDSet
+	// MariaDBGTIDs is only set for MariaDB servers.
+	MariaDBGTIDs binlog.MariaDBGTIDSet
+}
+
+// ServerInfo contains server details that affect replication.
+type ServerInfo struct {
+	Version  string
+	Flavor   binlog.Flavor
+	ServerID uint32
+	// ServerUUID is empty for MariaDB servers.
+	ServerUUID string
+	// LogBin is true if binary logging is enabled.
+	LogBin         bool
+	BinlogFormat   string
+	BinlogRowImage string
+}
+
+// Binary log formats.
+const (
+	BinlogFormatRow       = "ROW"
+	BinlogFormatStatement = "STATEMENT"
+	BinlogFormatMixed     = "MIXED"
+)
+
+// erParseError is the code of the error the server reports for a statement
+// it doesn't understand.
+const erParseError = 1064
+
+var (
+	// ErrBinlogDisabled is returned when master has binary logging disabled.
+	ErrBinlogDisabled = errors.New("Binary logging is disabled")
+	// ErrNotRowFormat is returned when master doesn't use row-based binary
+	// logging format. Changes can't be decoded from statements.
+	ErrNotRowFormat = errors.New("Binary log format is not ROW")
+	// ErrServerIDConflict is returned when replica server ID is the same as
+	// master's.
+	ErrServerIDConflict = errors.New("Replica server ID is the same as master's")
+)
+
+// ListBinaryLogs returns binary log files present on master in order they were
+// created.
+func ListBinaryLogs(ctx context.Context, db *sql.DB) ([]BinaryLog, error) {
+	rows, err := queryRows(ctx, db, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]BinaryLog, len(rows))
+	for i, row := range rows {
+		logs[i].File = row["Log_name"]
+		logs[i].Size, _ = strconv.ParseUint(row["File_size"], 10, 64)
+	}
+	return logs, nil
+}
+
+// GetMasterStatus returns current position of master in its binary log.
+func GetMasterStatus(ctx context.Context, db *sql.DB) (MasterStatus, error) {
+	var ms MasterStatus
+	rows, err := queryRows(ctx, db, "SHOW MASTER STATUS")
+	if isParseError(err) {
+		// Statement was renamed in MySQL 8.4
+		rows, err = queryRows(ctx, db, "SHOW BINARY LOG STATUS")
+	}
+	if err != nil {
+		return ms, err
+	}
+	if len(rows) == 0 {
+		return ms, ErrBinlogDisabled
+	}
+
+	row := rows[0]
+	ms.File = row["File"]
+	if ms.Offset, err = strconv.ParseUint(row["Position"], 10, 64); err != nil {
+		return ms, errors.Annotate(err, "parse position")
+	}
+	if ms.ExecutedGTIDs, err = binlog.ParseGTIDSet(row["Executed_Gtid_Set"]); err != nil {
+		return ms, errors.Annotate(err, "parse executed gtid set")
+	}
+
+	vars, err := getVariables(ctx, db, "version", "gtid_binlog_pos")
+	if err != nil {
+		return ms, err
+	}
+	if binlog.DetectFlavor(vars["version"]) == binlog.FlavorMariaDB {
+		if ms.MariaDBGTIDs, err = binlog.ParseMariaDBGTIDSet(vars["gtid_binlog_pos"]); err != nil {
+			return ms, errors.Annotate(err, "parse mariadb gtid position")
+		}
+	}
+	return ms, nil
+}
+
+// GetServerInfo returns server details that affect replication.
+func GetServerInfo(ctx context.Context, db *sql.DB) (ServerInfo, error) {
+	var si ServerInfo
+	vars, err := getVariables(ctx, db,
+		"version", "server_id", "server_uuid", "log_bin", "binlog_format", "binlog_row_image")
+	if err != nil {
+		return si, err
+	}
+
+	si.Version = vars["version"]
+	si.Flavor = binlog.DetectFlavor(si.Version)
+	id, err := strconv.ParseUint(vars["server_id"], 10, 32)
+	if err != nil {
+		return si, errors.Annotate(err, "parse server id")
+	}
+	si.ServerID = uint32(id)
+	si.ServerUUID = vars["server_uuid"]
+	si.LogBin = vars["log_bin"] == "ON" || vars["log_bin"] == "1"
+	si.BinlogFormat = vars["binlog_format"]
+	si.BinlogRowImage = vars["binlog_row_image"]
+	return si, nil
+}
+
+// Validate checks that a replica with given configuration could decode changes
+// from the binary log of this server.
+func (si ServerInfo) Validate(sc driver.Config) error {
+	switch {
+	case !si.LogBin:
+		return ErrBinlogDisabled
+	case si.BinlogFormat != BinlogFormatRow:
+		return ErrNotRowFormat
+	case si.ServerID == sc.ServerID:
+		return ErrServerIDConflict
+	}
+	return nil
+}
+
+// validateServer checks replication preconditions before starting a dump.
+func validateServer(ctx context.Context, dsn string, sc driver.Config) error {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return errors.Annotate(err, "establish connection")
+	}
+	defer db.Close()
+
+	si, err := GetServerInfo(ctx, db)
+	if err != nil {
+		return errors.Annotate(err, "get server info")
+	}
+	return si.Validate(sc)
+}
+
+// getVariables returns values of given global variables. Variables that don't
+// exist on the server are omitted.
+func getVariables(ctx context.Context, db *sql.DB, names ...string) (map[string]string, error) {
+	query := "SHOW GLOBAL VARIABLES WHERE Variable_name IN ("
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		if i > 0 {
+			query += ", "
+		}
+		query += "?"
+		args[i] = name
+	}
+	query += ")"
+
+	rows, err := queryRows(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(rows))
+	for _, row := range rows {
+		vars[row["Variable_name"]] = row["Value"]
+	}
+	return vars, nil
+}
+
+// isParseError returns true if the server failed to parse the statement.
+func isParseError(err error) bool {
+	me, ok := errors.Cause(err).(*mysql.MySQLError)
+	return ok && me.Number == erParseError
+}
+
+// queryRows returns query results as a list of column name to value maps. Set
+// of columns returned by administrative statements depends on server version.
+func queryRows(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]interface{}, len(cols))
+	for i := range vals {
+		vals[i] = new(sql.RawBytes)
+	}
+
+	var res []map[string]string
+	for rows.Next() {
+		if err := rows.Scan(vals...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(cols))
+		for i, col := range cols {
+			row[col] = string(*vals[i].(*sql.RawBytes))
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,178 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+func TestServerInfoValidate(t *testing.T) {
+	valid := ServerInfo{ServerID: 1, LogBin: true, BinlogFormat: BinlogFormatRow}
+	sc := driver.Config{ServerID: 1000}
+	if err := valid.Validate(sc); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		mod func(si *ServerInfo)
+		err error
+	}{
+		{func(si *ServerInfo) { si.LogBin = false }, ErrBinlogDisabled},
+		{func(si *ServerInfo) { si.BinlogFormat = BinlogFormatMixed }, ErrNotRowFormat},
+		{func(si *ServerInfo) { si.BinlogFormat = BinlogFormatStatement }, ErrNotRowFormat},
+		{func(si *ServerInfo) { si.ServerID = 1000 }, ErrServerIDConflict},
+	}
+	for _, test := range tests {
+		si := valid
+		test.mod(&si)
+		if err := si.Validate(sc); err != test.err {
+			t.Errorf("Expected %v for %+v, got %v", test.err, si, err)
+		}
+	}
+}
+
+func TestGetMasterStatus(t *testing.T) {
+	const sid = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	tests := []struct {
+		name    string
+		results map[string]testResult
+		exp     string
+	}{
+		{"MySQL 8.0", map[string]testResult{
+			"SHOW MASTER STATUS": {
+				cols: []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"},
+				rows: [][]string{{"mysql-bin.000002", "1234", "", "", sid + ":1-5"}},
+			},
+			"SHOW GLOBAL VARIABLES": {
+				cols: []string{"Variable_name", "Value"},
+				rows: [][]string{{"version", "8.0.32"}},
+			},
+		}, "mysql-bin.000002:1234 " + sid + ":1-5 "},
+		{"MySQL 8.4", map[string]testResult{
+			"SHOW BINARY LOG STATUS": {
+				cols: []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"},
+				rows: [][]string{{"mysql-bin.000003", "4", "", "", ""}},
+			},
+			"SHOW GLOBAL VARIABLES": {
+				cols: []string{"Variable_name", "Value"},
+				rows: [][]string{{"version", "8.4.0"}},
+			},
+		}, "mysql-bin.000003:4  "},
+		{"MariaDB", map[string]testResult{
+			"SHOW MASTER STATUS": {
+				cols: []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB"},
+				rows: [][]string{{"mariadb-bin.000001", "328", "", ""}},
+			},
+			"SHOW GLOBAL VARIABLES": {
+				cols: []string{"Variable_name", "Value"},
+				rows: [][]string{{"gtid_binlog_pos", "0-1-100"}, {"version", "10.11.6-MariaDB"}},
+			},
+		}, "mariadb-bin.000001:328  0-1-100"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ms, err := GetMasterStatus(context.Background(), testDB(test.results))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			res := fmt.Sprintf("%s:%d %s %s", ms.File, ms.Offset, ms.ExecutedGTIDs.String(), ms.MariaDBGTIDs.String())
+			if res != test.exp {
+				t.Errorf("Expected status %q, got %q", test.exp, res)
+			}
+		})
+	}
+
+	_, err := GetMasterStatus(context.Background(), testDB(map[string]testResult{
+		"SHOW MASTER STATUS": {cols: []string{"File", "Position"}},
+	}))
+	if err != ErrBinlogDisabled {
+		t.Errorf("Expected error %v, got %v", ErrBinlogDisabled, err)
+	}
+
+	// Only parse errors are retried with the new statement
+	denied := &mysql.MySQLError{Number: 1227, Message: "Access denied"}
+	_, err = GetMasterStatus(context.Background(), testDB(map[string]testResult{
+		"SHOW MASTER STATUS": {err: denied},
+		"SHOW BINARY LOG STATUS": {
+			cols: []string{"File", "Position"},
+			rows: [][]string{{"mysql-bin.000003", "4"}},
+		},
+	}))
+	if err != denied {
+		t.Errorf("Expected error %v, got %v", denied, err)
+	}
+}
+
+// testResult is a result set returned by the test database. Query fails if
+// the error is set.
+type testResult struct {
+	cols []string
+	rows [][]string
+	err  error
+}
+
+// testDB returns a database that responds to queries starting with given
+// prefixes. Other queries fail with a parse error.
+func testDB(results map[string]testResult) *sql.DB {
+	return sql.OpenDB(testConnector{results})
+}
+
+type testConnector struct {
+	results map[string]testResult
+}
+
+func (c testConnector) Connect(context.Context) (sqldriver.Conn, error) { return testConn(c), nil }
+func (c testConnector) Driver() sqldriver.Driver                        { return nil }
+
+type testConn testConnector
+
+func (c testConn) Prepare(query string) (sqldriver.Stmt, error) {
+	for prefix, res := range c.results {
+		if strings.HasPrefix(query, prefix) {
+			if res.err != nil {
+				return nil, res.err
+			}
+			return testStmt(res), nil
+		}
+	}
+	return nil, &mysql.MySQLError{Number: erParseError, Message: "unexpected query: " + query}
+}
+func (c testConn) Close() error                 { return nil }
+func (c testConn) Begin() (sqldriver.Tx, error) { return nil, errors.New("not supported") }
+
+type testStmt testResult
+
+func (s testStmt) Close() error  { return nil }
+func (s testStmt) NumInput() int { return -1 }
+func (s testStmt) Exec([]sqldriver.Value) (sqldriver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s testStmt) Query([]sqldriver.Value) (sqldriver.Rows, error) {
+	return &testRows{testResult: testResult(s)}, nil
+}
+
+type testRows struct {
+	testResult
+	pos int
+}
+
+func (r *testRows) Columns() []string { return r.cols }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []sqldriver.Value) error {
+	if r.pos == len(r.rows) {
+		return io.EOF
+	}
+	for i, val := range r.rows[r.pos] {
+		dest[i] = []byte(val)
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,209 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+// Source is a source of binary log events. Each packet contains a single event
+// including its header. Source returns io.EOF once there are no more events
+// to read.
+type Source interface {
+	ReadPacket(ctx context.Context) ([]byte, error)
+	Close() error
+}
+
+// semiSyncSource is a source that acts as a semi-synchronous replica.
+type semiSyncSource interface {
+	AckRequested() bool
+	SemiSyncAck(pos binlog.Position) error
+}
+
+// checksumSource is a source that knows if events sent before the format
+// description event, like the fake rotate event, carry checksums.
+type checksumSource interface {
+	Checksum() bool
+}
+
+// eventReader reads events from a stream in binary log file format.
+type eventReader struct {
+	rd       *bufio.Reader
+	checksum bool
+}
+
+// bytesSource reads events from a slice of bytes in binary log file format.
+type bytesSource struct {
+	eventReader
+}
+
+// captureSource replays packets recorded with a Recorder.
+type captureSource struct {
+	rd io.Reader
+}
+
+// Recorder is a source that records every packet it reads from the underlying
+// source. Recorded packets could be replayed with a capture source.
+type Recorder struct {
+	src Source
+	w   io.Writer
+}
+
+var (
+	// ErrInvalidMagic is returned when a file is not a binary log file.
+	ErrInvalidMagic = errors.New("Not a binary log file")
+
+	binlogMagic = []byte{0xFE, 'b', 'i', 'n'}
+)
+
+// maxCapturedPacketSize is the size of the largest packet a capture could
+// contain, it matches the largest max_allowed_packet value.
+const maxCapturedPacketSize = 1 << 30
+
+// NewBytesSource creates a source that reads events from a slice of bytes in
+// binary log file format: a magic number followed by events.
+func NewBytesSource(data []byte) (Source, error) {
+	s := &bytesSource{eventReader{rd: bufio.NewReader(bytes.NewReader(data))}}
+	if err := s.readMagic(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadPacket reads next event.
+func (s *bytesSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.readEvent()
+}
+
+// Close does nothing.
+func (s *bytesSource) Close() error {
+	return nil
+}
+
+// NewCaptureSource creates a source that replays packets recorded with a
+// Recorder.
+func NewCaptureSource(rd io.Reader) Source {
+	return &captureSource{rd: rd}
+}
+
+// ReadPacket reads next recorded packet.
+func (s *captureSource) ReadPacket(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(s.rd, size[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Annotate(err, "read packet size")
+	}
+	n := mysql.DecodeUint32(size[:])
+	if n > maxCapturedPacketSize {
+		return nil, errors.Errorf("recorded packet size %d exceeds the limit", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(s.rd, data); err != nil {
+		return nil, errors.Annotate(err, "read packet")
+	}
+	return data, nil
+}
+
+// Close closes underlying reader if it is closable.
+func (s *captureSource) Close() error {
+	if c, ok := s.rd.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewRecorder creates a new recorder that reads packets from the given source
+// and writes them to the given writer.
+func NewRecorder(src Source, w io.Writer) *Recorder {
+	return &Recorder{src: src, w: w}
+}
+
+// ReadPacket reads next packet from the underlying source and records it.
+func (r *Recorder) ReadPacket(ctx context.Context) ([]byte, error) {
+	data, err := r.src.ReadPacket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	mysql.EncodeUint32(size[:], uint32(len(data)))
+	if _, err := r.w.Write(size[:]); err != nil {
+		return nil, errors.Annotate(err, "record packet size")
+	}
+	if _, err := r.w.Write(data); err != nil {
+		return nil, errors.Annotate(err, "record packet")
+	}
+	return data, nil
+}
+
+// Checksum returns true if the underlying source sends checksummed events
+// before the format description event.
+func (r *Recorder) Checksum() bool {
+	cs, ok := r.src.(checksumSource)
+	return ok && cs.Checksum()
+}
+
+// Close closes the underlying source.
+func (r *Recorder) Close() error {
+	return r.src.Close()
+}
+
+// readMagic reads and checks binary log magic number.
+func (er *eventReader) readMagic() error {
+	magic := make([]byte, len(binlogMagic))
+	if _, err := io.ReadFull(er.rd, magic); err != nil || !bytes.Equal(magic, binlogMagic) {
+		return ErrInvalidMagic
+	}
+	return nil
+}
+
+// readEvent reads next event. io.EOF is returned if the stream is over.
+func (er *eventReader) readEvent() ([]byte, error) {
+	const (
+		headerLen    = 19
+		eventLenPos  = 9
+		eventTypePos = 4
+	)
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(er.rd, header); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Annotate(err, "read event header")
+	}
+
+	evtLen := int(mysql.DecodeUint32(header[eventLenPos:]))
+	if evtLen < headerLen {
+		return nil, binlog.ErrInvalidHeader
+	}
+	data := make([]byte, evtLen)
+	copy(data, header)
+	if _, err := io.ReadFull(er.rd, data[headerLen:]); err != nil {
+		return nil, errors.Annotate(err, "read event")
+	}
+
+	// Checksum algorithm is tracked to be able to produce fake events
+	if binlog.EventType(data[eventTypePos]) == binlog.EventTypeFormatDescription {
+		var fde binlog.FormatDescriptionEvent
+		if err := fde.Decode(data[headerLen:]); err != nil {
+			return nil, errors.Annotate(err, "decode format description event")
+		}
+		er.checksum = fde.ServerDetails.ChecksumAlgorithm == binlog.ChecksumAlgorithmCRC32
+	}
+	return data, nil
+}
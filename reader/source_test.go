@@ -0,0 +1,73 @@
+package reader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/localhots/bocadillo/binlog"
+)
+
+func TestBytesSource(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	types := readTestEventTypes(t, NewFromSource(src))
+	expTypes := []binlog.EventType{binlog.EventTypeFormatDescription, binlog.EventTypeQuery}
+	if len(types) != len(expTypes) || types[0] != expTypes[0] || types[1] != expTypes[1] {
+		t.Errorf("Expected events %v, got %v", expTypes, types)
+	}
+}
+
+func TestCaptureSource(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+		testQueryEvent("testdb", "DROP TABLE foo"),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	var capture bytes.Buffer
+	recorded := readTestEventTypes(t, NewFromSource(NewRecorder(src, &capture)))
+	replayed := readTestEventTypes(t, NewFromSource(NewCaptureSource(&capture)))
+	if len(recorded) != len(events) || len(replayed) != len(recorded) {
+		t.Fatalf("Expected %d events to be recorded and replayed, got %v and %v", len(events), recorded, replayed)
+	}
+	for i := range recorded {
+		if recorded[i] != replayed[i] {
+			t.Errorf("Expected event #%d to be %s, got %s", i, recorded[i], replayed[i])
+		}
+	}
+}
+
+func TestCaptureSourceOversizedPacket(t *testing.T) {
+	// Size of a corrupted capture must not be trusted
+	src := NewCaptureSource(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF}))
+	if _, err := src.ReadPacket(context.Background()); err == nil {
+		t.Error("Expected oversized packet to be rejected")
+	}
+}
+
+func readTestEventTypes(t *testing.T, r *Reader) []binlog.EventType {
+	var types []binlog.EventType
+	for {
+		evt, err := r.ReadEvent(context.Background())
+		if err == io.EOF {
+			return types
+		}
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		types = append(types, evt.Header.Type)
+	}
+}
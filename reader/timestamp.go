@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql/driver"
+)
+
+var (
+	// ErrNoBinaryLogs is returned when master has no binary log files.
+	ErrNoBinaryLogs = errors.New("No binary logs")
+)
+
+// PositionAt finds the position of the first transaction that was logged at
+// or after the given time. Binary log files are searched by the timestamps of
+// their first events, the matching file is then scanned up to the first
+// transaction boundary. Position could be used as a starting point in
+// driver.Config, end of the binary log is returned if there are no such
+// transactions.
+func PositionAt(ctx context.Context, dsn string, sc driver.Config, t time.Time) (binlog.Position, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return binlog.Position{}, errors.Annotate(err, "establish connection")
+	}
+	defer db.Close()
+
+	logs, err := ListBinaryLogs(ctx, db)
+	if err != nil {
+		return binlog.Position{}, errors.Annotate(err, "list binary logs")
+	}
+	files := make([]string, len(logs))
+	for i, l := range logs {
+		files[i] = l.File
+	}
+	file, err := searchFiles(files, t, func(file string) (time.Time, error) {
+		return fileStartTime(ctx, dsn, sc, file)
+	})
+	if err != nil {
+		return binlog.Position{}, err
+	}
+
+	r, err := New(dsn, scanConfig(sc, file))
+	if err != nil {
+		return binlog.Position{}, err
+	}
+	defer r.Close()
+	return scanToTime(ctx, r, t)
+}
+
+// searchFiles returns the last of the files that was created at or before the
+// given time, or the first one if all of them were created later.
+func searchFiles(files []string, t time.Time, created func(file string) (time.Time, error)) (string, error) {
+	if len(files) == 0 {
+		return "", ErrNoBinaryLogs
+	}
+
+	var err error
+	i := sort.Search(len(files), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var ct time.Time
+		ct, err = created(files[i])
+		return ct.After(t)
+	})
+	if err != nil {
+		return "", err
+	}
+	if i > 0 {
+		i--
+	}
+	return files[i], nil
+}
+
+// fileStartTime returns the timestamp of the format description event that
+// begins given binary log file.
+func fileStartTime(ctx context.Context, dsn string, sc driver.Config, file string) (time.Time, error) {
+	src, err := NewConnSource(dsn, scanConfig(sc, file))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer src.Close()
+
+	r := NewFromSource(src)
+	for {
+		evt, err := r.ReadEvent(ctx)
+		if err == io.EOF {
+			return time.Time{}, errors.Annotate(binlog.ErrInvalidHeader, "read format description event")
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if evt.Header.Type == binlog.EventTypeFormatDescription {
+			return time.Unix(int64(evt.Header.Timestamp), 0), nil
+		}
+	}
+}
+
+// scanToTime reads events up to the first transaction boundary at or after the
+// given time and returns its position.
+func scanToTime(ctx context.Context, r *Reader, t time.Time) (binlog.Position, error) {
+	r.StopAt(StopCondition{Time: t})
+	for {
+		_, err := r.ReadEvent(ctx)
+		if err == ErrEndOfRange || err == io.EOF {
+			return r.Safepoint(), nil
+		}
+		if err != nil {
+			return binlog.Position{}, err
+		}
+	}
+}
+
+// scanConfig returns a configuration for a non-blocking dump of the given file
+// from the beginning.
+func scanConfig(sc driver.Config, file string) driver.Config {
+	sc.File = file
+	sc.Offset = 4
+	sc.GTIDSet = ""
+	sc.MariaDBGTIDSet = ""
+	sc.SemiSync = false
+	sc.NonBlocking = true
+	// Server was already queried for binary logs
+	sc.SkipValidation = true
+	return sc
+}
@@ -0,0 +1,83 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestSearchFiles(t *testing.T) {
+	files := []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}
+	created := func(file string) (time.Time, error) {
+		for i, f := range files {
+			if f == file {
+				return time.Unix(int64(1000*(i+1)), 0), nil
+			}
+		}
+		t.Fatalf("Unexpected file: %s", file)
+		return time.Time{}, nil
+	}
+
+	tests := map[int64]string{
+		500:  "mysql-bin.000001",
+		1000: "mysql-bin.000001",
+		1500: "mysql-bin.000001",
+		2000: "mysql-bin.000002",
+		3500: "mysql-bin.000003",
+	}
+	for ts, exp := range tests {
+		file, err := searchFiles(files, time.Unix(ts, 0), created)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if file != exp {
+			t.Errorf("Expected %s for %d, got %s", exp, ts, file)
+		}
+	}
+
+	if _, err := searchFiles(nil, time.Now(), created); err != ErrNoBinaryLogs {
+		t.Errorf("Expected no binary logs error, got %v", err)
+	}
+}
+
+func TestScanToTime(t *testing.T) {
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testRotateEvent("mysql-bin.000001"),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(100),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (2)"),
+		testXIDEvent(101),
+	}
+	// Format description event is left without a timestamp since it comes
+	// before the rotate event that sets the file name
+	for i, ts := range []uint32{0, 0, 1000, 1000, 1001, 2000, 2000, 2001} {
+		mysql.EncodeUint32(events[i], ts)
+	}
+	data := testFileContents(events...)
+
+	tests := map[int64]uint64{
+		500:  4,
+		1500: uint64(testFileSize(events[:5]...)),
+		2000: uint64(testFileSize(events[:5]...)),
+		2500: uint64(testFileSize(events...)),
+	}
+	for ts, exp := range tests {
+		src, err := NewBytesSource(data)
+		if err != nil {
+			t.Fatalf("Failed to create source: %v", err)
+		}
+		pos, err := scanToTime(context.Background(), NewFromSource(src), time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if exp := (binlog.Position{File: "mysql-bin.000001", Offset: exp}); pos != exp {
+			t.Errorf("Expected %v for %d, got %v", exp, ts, pos)
+		}
+	}
+}
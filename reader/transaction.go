@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+)
+
+// Transaction is a group of changes that were committed together.
+type Transaction struct {
+	// GTID is nil for anonymous transactions.
+	GTID *binlog.GTIDEvent
+	// MariaDBGTID is set for transactions read from a MariaDB server.
+	MariaDBGTID *binlog.MariaDBGTID
+	// XID is set for transactions that modify tables of an XA-capable storage
+	// engine.
+	XID uint64
+	// CommitTime is the time of commit on the server that wrote the binary
+	// log. Second precision is used for servers that don't write commit
+	// timestamps into GTID events.
+	CommitTime time.Time
+	// Start is the position of the first event of the transaction, End is the
+	// position right after the last one.
+	Start binlog.Position
+	End   binlog.Position
+	// Queries contains statements that were logged in statement format,
+	// including DDL.
+	Queries []string
+	// Changes contains decoded rows events in order they were logged.
+	Changes []RowsChange
+}
+
+// RowsChange contains rows affected by a single rows event.
+type RowsChange struct {
+	Type  binlog.EventType
+	Table binlog.TableDescription
+	Rows  [][]interface{}
+}
+
+// NextTransaction reads events until a complete transaction is collected and
+// returns it. Events that are not part of any transaction are skipped. If an
+// error occurs in the middle of a transaction, collected events are kept and
+// the next call continues with the same transaction.
+func (r *Reader) NextTransaction(ctx context.Context) (*Transaction, error) {
+	for {
+		atBoundary := r.gtid == nil && !r.inTx
+		evt, err := r.ReadEvent(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.tx == nil {
+			// Tail of a transaction that started before the reader did is
+			// skipped
+			if !atBoundary || !startsTransaction(evt.Header.Type) {
+				continue
+			}
+			r.tx = &Transaction{Start: binlog.Position{File: r.state.File, Offset: evt.Offset}}
+		}
+		tx := r.tx
+
+		switch evt.Header.Type {
+		case binlog.EventTypeGTID:
+			var ge binlog.GTIDEvent
+			if err := ge.Decode(evt.Buffer); err != nil {
+				return nil, errors.Annotate(err, "decode gtid event")
+			}
+			tx.GTID = &ge
+
+		case binlog.EventTypeMariaDBGTID:
+			var ge binlog.MariaDBGTIDEvent
+			if err := ge.Decode(evt.Buffer, evt.Header.ServerID); err != nil {
+				return nil, errors.Annotate(err, "decode mariadb gtid event")
+			}
+			tx.MariaDBGTID = &ge.GTID
+
+		case binlog.EventTypeQuery, binlog.EventTypeMariaDBQueryCompressed:
+			qe, err := evt.DecodeQuery()
+			if err != nil {
+				return nil, errors.Annotate(err, "decode query event")
+			}
+			switch strings.ToUpper(string(qe.Query)) {
+			case "BEGIN", "COMMIT", "ROLLBACK":
+			default:
+				tx.Queries = append(tx.Queries, string(qe.Query))
+			}
+
+		case binlog.EventTypeXID:
+			var xe binlog.XIDEvent
+			if err := xe.Decode(evt.Buffer); err != nil {
+				return nil, errors.Annotate(err, "decode xid event")
+			}
+			tx.XID = xe.XID
+
+		default:
+			if evt.Table != nil {
+				re, err := evt.DecodeRows()
+				if err != nil {
+					return nil, errors.Annotate(err, "decode rows event")
+				}
+				tx.Changes = append(tx.Changes, RowsChange{
+					Type:  evt.Header.Type,
+					Table: *evt.Table,
+					Rows:  re.Rows,
+				})
+			}
+		}
+
+		// Reader has reached transaction boundary
+		if r.gtid == nil && !r.inTx {
+			r.tx = nil
+			tx.End = r.state
+			tx.CommitTime = time.Unix(int64(evt.Header.Timestamp), 0)
+			if tx.GTID != nil && tx.GTID.ImmediateCommitTimestamp > 0 {
+				tx.CommitTime = tx.GTID.ImmediateCommitTime()
+			}
+			return tx, nil
+		}
+	}
+}
+
+// startsTransaction returns true if an event of a given type could be the
+// first event of a transaction read at a transaction boundary: a GTID event,
+// BEGIN or a standalone statement.
+func startsTransaction(et binlog.EventType) bool {
+	switch et {
+	case binlog.EventTypeGTID,
+		binlog.EventTypeAnonymousGTID,
+		binlog.EventTypeQuery,
+		binlog.EventTypeMariaDBGTID,
+		binlog.EventTypeMariaDBQueryCompressed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,146 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/localhots/bocadillo/binlog"
+	"github.com/localhots/bocadillo/mysql"
+)
+
+func TestNextTransaction(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testGTIDEvent(sid, 1),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+		testGTIDEvent(sid, 2),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (2)"),
+		testXIDEvent(100),
+	}
+	src, err := NewBytesSource(testFileContents(events...))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	r := NewFromSource(src)
+	ctx := context.Background()
+
+	tx, err := r.NextTransaction(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.GTID == nil || tx.GTID.SID != sid || tx.GTID.GNO != 1 {
+		t.Errorf("Unexpected GTID: %+v", tx.GTID)
+	}
+	if len(tx.Queries) != 1 || tx.Queries[0] != "CREATE TABLE foo (id INT)" {
+		t.Errorf("Unexpected queries: %q", tx.Queries)
+	}
+	if exp := uint64(testFileSize(events[:1]...)); tx.Start.Offset != exp {
+		t.Errorf("Expected transaction to start at %d, got %d", exp, tx.Start.Offset)
+	}
+	if exp := uint64(testFileSize(events[:3]...)); tx.End.Offset != exp {
+		t.Errorf("Expected transaction to end at %d, got %d", exp, tx.End.Offset)
+	}
+
+	tx, err = r.NextTransaction(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.GTID == nil || tx.GTID.GNO != 2 {
+		t.Errorf("Unexpected GTID: %+v", tx.GTID)
+	}
+	if len(tx.Queries) != 2 {
+		t.Errorf("Unexpected queries: %q", tx.Queries)
+	}
+	if tx.XID != 100 {
+		t.Errorf("Expected XID 100, got %d", tx.XID)
+	}
+	if exp := uint64(testFileSize(events...)); tx.End.Offset != exp {
+		t.Errorf("Expected transaction to end at %d, got %d", exp, tx.End.Offset)
+	}
+
+	if exp := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-2"; r.State().ExecutedGTIDs.String() != exp {
+		t.Errorf("Expected executed GTID set %q, got %q", exp, r.State().ExecutedGTIDs.String())
+	}
+}
+
+func TestNextTransactionInterrupted(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testGTIDEvent(sid, 1),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (2)"),
+		testXIDEvent(100),
+	}
+	setTestOffsets(events...)
+	src := &testPacketSource{packets: events[:4]}
+	r := NewFromSource(src)
+
+	// Master stays silent in the middle of the transaction
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.NextTransaction(ctx); errors.Cause(err) != context.DeadlineExceeded {
+		t.Fatalf("Expected context deadline error, got %v", err)
+	}
+
+	src.packets = events[4:]
+	tx, err := r.NextTransaction(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.GTID == nil || tx.GTID.GNO != 1 {
+		t.Errorf("Unexpected GTID: %+v", tx.GTID)
+	}
+	if len(tx.Queries) != 2 || tx.XID != 100 {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+	if exp := uint64(testFileSize(events[:1]...)); tx.Start.Offset != exp {
+		t.Errorf("Expected transaction to start at %d, got %d", exp, tx.Start.Offset)
+	}
+}
+
+func TestNextTransactionSkipsTail(t *testing.T) {
+	sid, _ := binlog.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	events := [][]byte{
+		testFormatDescriptionEvent(),
+		testGTIDEvent(sid, 1),
+		testQueryEvent("testdb", "BEGIN"),
+		testQueryEvent("testdb", "INSERT INTO foo VALUES (1)"),
+		testXIDEvent(100),
+		testGTIDEvent(sid, 2),
+		testQueryEvent("testdb", "CREATE TABLE foo (id INT)"),
+	}
+	setTestOffsets(events...)
+	r := NewFromSource(&testPacketSource{packets: events})
+
+	// Reader that starts in the middle of a transaction skips its tail
+	r.inTx = true
+	tx, err := r.NextTransaction(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if tx.GTID == nil || tx.GTID.GNO != 2 {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+}
+
+func testGTIDEvent(sid binlog.SID, gno uint64) []byte {
+	body := make([]byte, 1+16+8+1+8+8)
+	body[0] = 1
+	copy(body[1:], sid[:])
+	mysql.EncodeUint64(body[17:], gno)
+	body[25] = 2
+	return testEvent(binlog.EventTypeGTID, body)
+}
+
+func testXIDEvent(xid uint64) []byte {
+	body := make([]byte, 8)
+	mysql.EncodeUint64(body, xid)
+	return testEvent(binlog.EventTypeXID, body)
+}
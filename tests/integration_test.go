@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
@@ -9,7 +10,6 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 
-	"github.com/localhots/bocadillo/binlog"
 	"github.com/localhots/bocadillo/mysql/driver"
 	"github.com/localhots/bocadillo/reader"
 )
@@ -25,7 +25,11 @@ func TestMain(m *testing.M) {
 	}
 
 	if conf.File == "" {
-		pos := getLatestOffset(conn)
+		ms, err := reader.GetMasterStatus(context.Background(), conn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pos := ms.Position
 		log.Printf("File is not set, using latest from master: %s @ %d", pos.File, pos.Offset)
 		conf.File = pos.File
 		conf.Offset = uint32(pos.Offset)
@@ -70,15 +74,3 @@ func getConfig() (dsn string, conf driver.Config) {
 
 	return
 }
-
-func getLatestOffset(conn *sql.DB) binlog.Position {
-	var pos binlog.Position
-	var _discard interface{}
-	err := conn.QueryRow("SHOW MASTER STATUS").Scan(
-		&pos.File, &pos.Offset, &_discard, &_discard, &_discard,
-	)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return pos
-}